@@ -0,0 +1,192 @@
+// Package baseline records the results of the reference backend for a script,
+// so that other backends can be compared with them later without the reference.
+package baseline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidiff/diff"
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+// Baseline is the results of all statements of a script in a backend.
+type Baseline struct {
+	// Backend and Address identify the backend which the results come from.
+	Backend    string   `json:"backend"`
+	Address    string   `json:"address"`
+	Statements []*Entry `json:"statements"`
+}
+
+// Entry is the result of a statement.
+type Entry struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Query is the statement in the script, and Rendered is the executed one
+	// which differs if the statement is a template.
+	Query        string             `json:"query"`
+	Rendered     string             `json:"rendered"`
+	Error        *Error             `json:"error,omitempty"`
+	Columns      []string           `json:"columns,omitempty"`
+	ColumnTypes  []diff.ColumnType  `json:"column_types,omitempty"`
+	Rows         Rows               `json:"rows,omitempty"`
+	AffectedRows int64              `json:"affected_rows"`
+	LastInsertID int64              `json:"last_insert_id"`
+	WarningCount int                `json:"warning_count"`
+	Warnings     []executor.Warning `json:"warnings,omitempty"`
+	// WarningsFetched reports whether the warnings are fetched when recording,
+	// the warnings aren't compared if not.
+	WarningsFetched   bool   `json:"warnings_fetched"`
+	WarningsError     string `json:"warnings_error,omitempty"`
+	WarningCountError string `json:"warning_count_error,omitempty"`
+}
+
+// Error is an error returned by the backend, Code and SQLState are empty if the
+// error isn't returned by the server (e.g. network errors).
+type Error struct {
+	Code     uint16 `json:"code,omitempty"`
+	SQLState string `json:"sqlstate,omitempty"`
+	Message  string `json:"message"`
+}
+
+// NewError records the error, it returns nil if err is nil.
+func NewError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return &Error{Code: me.Number, SQLState: string(me.SQLState[:]), Message: me.Message}
+	}
+	return &Error{Message: err.Error()}
+}
+
+// Err restores the recorded error, server errors are restored as *mysql.MySQLError
+// so that they are compared by the error code and SQLSTATE.
+func (e *Error) Err() error {
+	if e == nil {
+		return nil
+	}
+	if e.Code == 0 {
+		return errors.New(e.Message)
+	}
+	me := &mysql.MySQLError{Number: e.Code, Message: e.Message}
+	copy(me.SQLState[:], e.SQLState)
+	return me
+}
+
+// Rows are the rows of a result set, SQL NULL values are nil.
+type Rows [][]*string
+
+// NewRows records the rows of a result set.
+func NewRows(rows [][]sql.NullString) Rows {
+	var result Rows
+	for _, row := range rows {
+		cells := make([]*string, len(row))
+		for i, cell := range row {
+			if cell.Valid {
+				s := cell.String
+				cells[i] = &s
+			}
+		}
+		result = append(result, cells)
+	}
+	return result
+}
+
+// NullStrings restores the recorded rows.
+func (rows Rows) NullStrings() [][]sql.NullString {
+	var result [][]sql.NullString
+	for _, row := range rows {
+		cells := make([]sql.NullString, len(row))
+		for i, cell := range row {
+			if cell != nil {
+				cells[i] = sql.NullString{String: *cell, Valid: true}
+			}
+		}
+		result = append(result, cells)
+	}
+	return result
+}
+
+// NewEntry records the result of a statement, fetchWarnings reports whether the
+// warnings are fetched after the statement.
+func NewEntry(stmt *script.Statement, result *executor.QueryResult, fetchWarnings bool) *Entry {
+	return &Entry{
+		File:         stmt.File,
+		Line:         stmt.Line,
+		Query:        stmt.Text,
+		Rendered:     result.Rendered,
+		Error:        NewError(result.Error),
+		Columns:      result.Columns,
+		ColumnTypes:  result.ColumnTypes,
+		Rows:         NewRows(result.Rows),
+		AffectedRows: result.AffectedRows,
+		LastInsertID: result.LastInsertID,
+		WarningCount: result.WarningCount,
+		Warnings:     result.Warnings,
+
+		WarningsFetched:   fetchWarnings,
+		WarningsError:     errorText(result.WarningsError),
+		WarningCountError: errorText(result.WarningCountError),
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func textError(text string) error {
+	if text == "" {
+		return nil
+	}
+	return errors.New(text)
+}
+
+// Result restores the recorded result as if it's returned by the backend.
+func (e *Entry) Result(backend *executor.Backend) *executor.QueryResult {
+	return &executor.QueryResult{
+		Backend:      backend,
+		Rendered:     e.Rendered,
+		Error:        e.Error.Err(),
+		Columns:      e.Columns,
+		ColumnTypes:  e.ColumnTypes,
+		Rows:         e.Rows.NullStrings(),
+		AffectedRows: e.AffectedRows,
+		LastInsertID: e.LastInsertID,
+		WarningCount: e.WarningCount,
+		Warnings:     e.Warnings,
+
+		WarningsError:     textError(e.WarningsError),
+		WarningCountError: textError(e.WarningCountError),
+	}
+}
+
+// Load reads a baseline file.
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Save writes the baseline to the file.
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
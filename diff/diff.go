@@ -0,0 +1,373 @@
+// Package diff compares result sets row by row and cell by cell.
+package diff
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ResultSet is a result set to compare, SQL NULL values are the cells with Valid false.
+type ResultSet struct {
+	Columns []string
+	// Types are the column types, which are optional and only used by the rules.
+	Types []ColumnType
+	Rows  [][]sql.NullString
+}
+
+// Kind is the kind of a row in the diff.
+type Kind int
+
+const (
+	// Equal means the row exists in both result sets.
+	Equal Kind = iota
+	// Missing means the row only exists in the reference result set.
+	Missing
+	// Extra means the row only exists in the compared result set.
+	Extra
+	// Changed means the row exists in both result sets with some different cells.
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Equal:
+		return "equal"
+	case Missing:
+		return "missing"
+	case Extra:
+		return "extra"
+	case Changed:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the kind by its name, e.g. in JSON.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Row is a row in the diff of two result sets.
+type Row struct {
+	Kind Kind `json:"kind"`
+	// Ref and Other are the indexes of the row in the reference and the compared
+	// result sets, -1 if the row doesn't exist in the corresponding side.
+	Ref   int `json:"ref"`
+	Other int `json:"other"`
+	// Cells are the indexes of different cells of Changed rows.
+	Cells []int `json:"cells,omitempty"`
+}
+
+// Result is the diff of two result sets.
+type Result struct {
+	// Columns are the indexes of columns whose names are different, the columns
+	// only exist in one side are included too.
+	Columns []int
+	// Metadata are the differences of column metadata if compared.
+	Metadata []ColumnDiff
+	Rows     []Row
+}
+
+// Equal reports whether the two result sets are the same.
+func (r *Result) Equal() bool {
+	if len(r.Columns) > 0 || len(r.Metadata) > 0 {
+		return false
+	}
+	for _, row := range r.Rows {
+		if row.Kind != Equal {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of rows of the kind.
+func (r *Result) Count(kind Kind) int {
+	var n int
+	for _, row := range r.Rows {
+		if row.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// String describes the differences briefly, e.g. `2 changed, 1 missing rows`.
+func (r *Result) String() string {
+	var parts []string
+	if len(r.Columns) > 0 {
+		parts = append(parts, "different columns")
+	}
+	if len(r.Metadata) > 0 {
+		parts = append(parts, "different column metadata")
+	}
+	var counts []string
+	for _, kind := range []Kind{Changed, Missing, Extra} {
+		if n := r.Count(kind); n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	if len(counts) > 0 {
+		parts = append(parts, strings.Join(counts, ", ")+" rows")
+	}
+	if len(parts) == 0 {
+		return "equal"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Options controls how result sets are compared.
+type Options struct {
+	// Unordered compares the result sets as multisets, i.e. the order of rows is ignored.
+	Unordered bool
+	// Rules normalize the cells before comparing, nil to compare the cells as they are.
+	Rules *Rules
+	// Metadata compares the column metadata, i.e. the column types.
+	Metadata bool
+}
+
+// Compare compares the result set other with the reference result set ref. Rows are
+// aligned by the longest common subsequence, and the unaligned rows between two
+// aligned rows are paired as Changed rows if both sides have the same number of
+// columns, or else reported as Missing and Extra rows. If the comparison is
+// unordered, both sides are sorted before aligning. The cells are normalized by
+// the rules according to the column types before comparing. The column metadata
+// is compared if required.
+func Compare(ref, other *ResultSet, opts *Options) *Result {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := compare(ref, other, opts)
+	if opts.Metadata {
+		r.Metadata = compareMetadata(ref, other)
+	}
+	return r
+}
+
+func compare(ref, other *ResultSet, opts *Options) *Result {
+	if opts.Rules != nil {
+		ref, other = opts.Rules.normalize(ref), opts.Rules.normalize(other)
+	}
+	if !opts.Unordered {
+		return compareOrdered(ref, other, opts.Rules)
+	}
+	// Float columns are sorted by the values, so that the rows different within
+	// the float tolerances are sorted to the same positions and paired
+	floats := floatColumns(ref, other, opts.Rules)
+	sortedRef, refIndexes := sortRows(ref, floats)
+	sortedOther, otherIndexes := sortRows(other, floats)
+	r := compareOrdered(sortedRef, sortedOther, opts.Rules)
+	for i := range r.Rows {
+		if row := &r.Rows[i]; row.Ref >= 0 {
+			row.Ref = refIndexes[row.Ref]
+		}
+		if row := &r.Rows[i]; row.Other >= 0 {
+			row.Other = otherIndexes[row.Other]
+		}
+	}
+	return r
+}
+
+// sortRows returns a copy of the result set with sorted rows, and the original
+// indexes of the sorted rows. The float columns are sorted by the values.
+func sortRows(rs *ResultSet, floats []bool) (*ResultSet, []int) {
+	indexes := make([]int, len(rs.Rows))
+	for i := range rs.Rows {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return lessRow(rs.Rows[indexes[i]], rs.Rows[indexes[j]], floats)
+	})
+	sorted := &ResultSet{Columns: rs.Columns, Types: rs.Types, Rows: make([][]sql.NullString, len(rs.Rows))}
+	for i, index := range indexes {
+		sorted.Rows[i] = rs.Rows[index]
+	}
+	return sorted, indexes
+}
+
+// lessRow orders the rows cell by cell, NULL is the smallest.
+func lessRow(a, b []sql.NullString, floats []bool) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, y := a[i], b[i]
+		if !x.Valid || !y.Valid {
+			if x.Valid != y.Valid {
+				return !x.Valid
+			}
+			continue
+		}
+		if i < len(floats) && floats[i] {
+			fx, errx := strconv.ParseFloat(x.String, 64)
+			fy, erry := strconv.ParseFloat(y.String, 64)
+			if errx == nil && erry == nil {
+				if fx != fy {
+					return fx < fy
+				}
+				continue
+			}
+		}
+		if x.String != y.String {
+			return x.String < y.String
+		}
+	}
+	return len(a) < len(b)
+}
+
+// floatColumns returns the columns which the float tolerances are applied to,
+// which are floats in either side.
+func floatColumns(ref, other *ResultSet, rules *Rules) []bool {
+	floats := make([]bool, len(ref.Columns))
+	for i := range floats {
+		floats[i] = rules != nil && (columnFamily(ref.Types, i) == floatFamily || columnFamily(other.Types, i) == floatFamily)
+	}
+	return floats
+}
+
+func compareOrdered(ref, other *ResultSet, rules *Rules) *Result {
+	r := &Result{Columns: compareColumns(ref.Columns, other.Columns)}
+	comparable := len(ref.Columns) == len(other.Columns)
+	floats := floatColumns(ref, other, rules)
+
+	// Encode every distinct row as a rune so that the rows can be aligned by the
+	// Myers' diff algorithm efficiently.
+	keys := map[string]rune{}
+	encode := func(rows [][]sql.NullString) []rune {
+		runes := make([]rune, len(rows))
+		for i, row := range rows {
+			k := key(row)
+			if _, found := keys[k]; !found {
+				keys[k] = encodeIndex(len(keys))
+			}
+			runes[i] = keys[k]
+		}
+		return runes
+	}
+
+	var refIndex, otherIndex int
+	var deleted, inserted []int
+	flush := func() {
+		paired := 0
+		if comparable {
+			paired = len(deleted)
+			if len(inserted) < paired {
+				paired = len(inserted)
+			}
+		}
+		for i := 0; i < paired; i++ {
+			row := Row{Kind: Changed, Ref: deleted[i], Other: inserted[i]}
+			row.Cells = compareCells(ref.Rows[deleted[i]], other.Rows[inserted[i]], floats, rules)
+			if len(row.Cells) == 0 {
+				// Only different in the float tolerances
+				row.Kind = Equal
+			}
+			r.Rows = append(r.Rows, row)
+		}
+		for _, i := range deleted[paired:] {
+			r.Rows = append(r.Rows, Row{Kind: Missing, Ref: i, Other: -1})
+		}
+		for _, i := range inserted[paired:] {
+			r.Rows = append(r.Rows, Row{Kind: Extra, Ref: -1, Other: i})
+		}
+		deleted, inserted = deleted[:0], inserted[:0]
+	}
+	refRunes, otherRunes := encode(ref.Rows), encode(other.Rows)
+	if len(keys) > maxKeys {
+		// Too many distinct rows to encode as runes, so the rows are paired by
+		// positions instead, which may report more changed rows than aligning
+		// but never hides a difference.
+		for i := range ref.Rows {
+			deleted = append(deleted, i)
+		}
+		for i := range other.Rows {
+			inserted = append(inserted, i)
+		}
+		flush()
+		return r
+	}
+	diffs := diffmatchpatch.New().DiffMainRunes(refRunes, otherRunes, false)
+	for _, d := range diffs {
+		n := len([]rune(d.Text))
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			flush()
+			for i := 0; i < n; i++ {
+				r.Rows = append(r.Rows, Row{Kind: Equal, Ref: refIndex, Other: otherIndex})
+				refIndex++
+				otherIndex++
+			}
+		case diffmatchpatch.DiffDelete:
+			for i := 0; i < n; i++ {
+				deleted = append(deleted, refIndex)
+				refIndex++
+			}
+		case diffmatchpatch.DiffInsert:
+			for i := 0; i < n; i++ {
+				inserted = append(inserted, otherIndex)
+				otherIndex++
+			}
+		}
+	}
+	flush()
+	return r
+}
+
+// maxKeys is the number of distinct rows which can be encoded by encodeIndex,
+// i.e. the runes except the surrogate halves.
+var maxKeys = int(utf8.MaxRune) + 1 - 0x800
+
+// encodeIndex maps the index to a rune, the surrogate halves are skipped because
+// they would be replaced by the replacement character in the diff texts.
+func encodeIndex(i int) rune {
+	if i >= 0xD800 {
+		i += 0x800
+	}
+	return rune(i)
+}
+
+func compareColumns(ref, other []string) []int {
+	var columns []int
+	for i := 0; i < len(ref) || i < len(other); i++ {
+		if i >= len(ref) || i >= len(other) || ref[i] != other[i] {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+func compareCells(ref, other []sql.NullString, floats []bool, rules *Rules) []int {
+	var cells []int
+	for i := range ref {
+		if equalCell(ref[i], other[i]) {
+			continue
+		}
+		if floats[i] && ref[i].Valid && other[i].Valid && rules.equalFloat(ref[i].String, other[i].String) {
+			continue
+		}
+		cells = append(cells, i)
+	}
+	return cells
+}
+
+func equalCell(a, b sql.NullString) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.String == b.String)
+}
+
+// key encodes a row into a string which keeps NULL distinct from any string.
+func key(row []sql.NullString) string {
+	var b strings.Builder
+	for _, cell := range row {
+		if !cell.Valid {
+			b.WriteString("N;")
+			continue
+		}
+		b.WriteString(strconv.Itoa(len(cell.String)))
+		b.WriteByte(':')
+		b.WriteString(cell.String)
+	}
+	return b.String()
+}
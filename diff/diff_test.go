@@ -0,0 +1,181 @@
+package diff
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func rows(values ...[]interface{}) [][]sql.NullString {
+	var result [][]sql.NullString
+	for _, row := range values {
+		var cells []sql.NullString
+		for _, v := range row {
+			if v == nil {
+				cells = append(cells, sql.NullString{})
+				continue
+			}
+			cells = append(cells, sql.NullString{String: v.(string), Valid: true})
+		}
+		result = append(result, cells)
+	}
+	return result
+}
+
+func TestCompare(t *testing.T) {
+	ref := &ResultSet{
+		Columns: []string{"a", "b"},
+		Rows:    rows([]interface{}{"1", "x"}, []interface{}{"2", ""}, []interface{}{"3", "z"}, []interface{}{"4", "w"}),
+	}
+	other := &ResultSet{
+		Columns: []string{"a", "b"},
+		Rows:    rows([]interface{}{"1", "x"}, []interface{}{"2", nil}, []interface{}{"4", "w"}, []interface{}{"5", "v"}),
+	}
+	r := Compare(ref, other, nil)
+	expected := []Row{
+		{Kind: Equal, Ref: 0, Other: 0},
+		{Kind: Changed, Ref: 1, Other: 1, Cells: []int{1}},
+		{Kind: Missing, Ref: 2, Other: -1},
+		{Kind: Equal, Ref: 3, Other: 2},
+		{Kind: Extra, Ref: -1, Other: 3},
+	}
+	if !reflect.DeepEqual(r.Rows, expected) {
+		t.Fatalf("expected %v, got %v", expected, r.Rows)
+	}
+	if r.Equal() {
+		t.Fatal("expected differences")
+	}
+	if s := r.String(); s != "1 changed, 1 missing, 1 extra rows" {
+		t.Fatalf("unexpected description %q", s)
+	}
+}
+
+func TestCompareColumns(t *testing.T) {
+	ref := &ResultSet{Columns: []string{"a", "b"}, Rows: rows([]interface{}{"1", "2"})}
+	other := &ResultSet{Columns: []string{"a", "c", "d"}, Rows: rows([]interface{}{"1", "2", "3"})}
+	r := Compare(ref, other, nil)
+	if !reflect.DeepEqual(r.Columns, []int{1, 2}) {
+		t.Fatalf("unexpected columns %v", r.Columns)
+	}
+	// Rows with different number of columns can't be paired
+	if r.Count(Missing) != 1 || r.Count(Extra) != 1 || r.Count(Changed) != 0 {
+		t.Fatalf("unexpected rows %v", r.Rows)
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	ref := &ResultSet{Columns: []string{"a"}, Rows: rows([]interface{}{"1"}, []interface{}{nil})}
+	if r := Compare(ref, ref, nil); !r.Equal() {
+		t.Fatalf("expected equal, got %v", r.Rows)
+	}
+}
+
+func TestCompareUnordered(t *testing.T) {
+	ref := &ResultSet{Columns: []string{"a"}, Rows: rows([]interface{}{"1"}, []interface{}{"2"}, []interface{}{"2"})}
+	other := &ResultSet{Columns: []string{"a"}, Rows: rows([]interface{}{"2"}, []interface{}{"1"}, []interface{}{"2"})}
+	if r := Compare(ref, other, nil); r.Equal() {
+		t.Fatal("expected differences in ordered comparison")
+	}
+	if r := Compare(ref, other, &Options{Unordered: true}); !r.Equal() {
+		t.Fatalf("expected equal in unordered comparison, got %v", r.Rows)
+	}
+
+	// Duplicated rows are counted
+	other.Rows = rows([]interface{}{"2"}, []interface{}{"1"}, []interface{}{"1"})
+	r := Compare(ref, other, &Options{Unordered: true})
+	if r.Count(Changed) != 1 {
+		t.Fatalf("expected 1 changed row, got %v", r.Rows)
+	}
+	for _, row := range r.Rows {
+		if row.Kind == Changed && ref.Rows[row.Ref][0].String != "2" {
+			t.Fatalf("expected original indexes, got %v", r.Rows)
+		}
+	}
+}
+
+func TestCompareTooManyRows(t *testing.T) {
+	// The rows are paired by positions if there are more distinct rows than runes
+	defer func(n int) { maxKeys = n }(maxKeys)
+	maxKeys = 2
+	ref := &ResultSet{Columns: []string{"a"}, Rows: rows([]interface{}{"1"}, []interface{}{"2"}, []interface{}{"3"})}
+	other := &ResultSet{Columns: []string{"a"}, Rows: rows([]interface{}{"1"}, []interface{}{"4"}, []interface{}{"3"}, []interface{}{"5"})}
+	expected := []Row{
+		{Kind: Equal, Ref: 0, Other: 0},
+		{Kind: Changed, Ref: 1, Other: 1, Cells: []int{0}},
+		{Kind: Equal, Ref: 2, Other: 2},
+		{Kind: Extra, Ref: -1, Other: 3},
+	}
+	if r := Compare(ref, other, nil); !reflect.DeepEqual(r.Rows, expected) {
+		t.Fatalf("expected %v, got %v", expected, r.Rows)
+	}
+	if r := Compare(ref, ref, &Options{Unordered: true}); !r.Equal() {
+		t.Fatalf("expected equal, got %v", r.Rows)
+	}
+}
+
+func TestCompareUnorderedFloats(t *testing.T) {
+	types := []ColumnType{{Type: "DOUBLE"}}
+	ref := &ResultSet{Columns: []string{"a"}, Types: types, Rows: rows([]interface{}{"10"}, []interface{}{"5"}, []interface{}{nil})}
+	other := &ResultSet{Columns: []string{"a"}, Types: types, Rows: rows([]interface{}{nil}, []interface{}{"5"}, []interface{}{"9.999999999999998"})}
+	rules := NewRules()
+	rules.FloatRelEpsilon = 1e-12
+	// 10 and 9.999999999999998 are on different sides of 5 if sorted by the text
+	if r := Compare(ref, other, &Options{Unordered: true, Rules: rules}); !r.Equal() {
+		t.Fatalf("expected equal within the tolerance in unordered comparison, got %v", r.Rows)
+	}
+	if r := Compare(ref, other, &Options{Unordered: true, Rules: NewRules()}); r.Count(Changed) != 1 {
+		t.Fatalf("expected 1 changed row without the tolerance, got %v", r.Rows)
+	}
+}
+
+func TestCompareRules(t *testing.T) {
+	types := []ColumnType{{Type: "DOUBLE"}, {Type: "DECIMAL"}, {Type: "DATETIME"}, {Type: "VARCHAR"}}
+	ref := &ResultSet{
+		Columns: []string{"a", "b", "c", "d"},
+		Types:   types,
+		Rows:    rows([]interface{}{"0.3333333333333333", "2.5000", "2020-01-01 00:00:00.123456", "Abc"}),
+	}
+	other := &ResultSet{
+		Columns: []string{"a", "b", "c", "d"},
+		Types:   types,
+		Rows:    rows([]interface{}{"0.33333333333333337", "2.50", "2020-01-01 00:00:00.123", "aBC"}),
+	}
+	if r := Compare(ref, other, nil); r.Count(Changed) != 1 || len(r.Rows[0].Cells) != 4 {
+		t.Fatalf("expected 4 changed cells without rules, got %v", r.Rows)
+	}
+	rules := NewRules()
+	rules.FloatRelEpsilon = 1e-12
+	rules.TrimDecimal = true
+	rules.TimePrecision = 3
+	rules.IgnoreCase = true
+	if r := Compare(ref, other, &Options{Rules: rules}); !r.Equal() {
+		t.Fatalf("expected equal with rules, got %v", r.Rows)
+	}
+
+	rules = NewRules()
+	rules.DecimalScale = 0
+	if r := Compare(ref, other, &Options{Rules: rules}); r.Rows[0].Kind != Changed || !reflect.DeepEqual(r.Rows[0].Cells, []int{0, 2, 3}) {
+		t.Fatalf("expected the decimal column to be equal, got %v", r.Rows)
+	}
+}
+
+func TestCompareMetadata(t *testing.T) {
+	ref := &ResultSet{
+		Columns: []string{"1 + 1.0"},
+		Types:   []ColumnType{{Type: "DECIMAL", Precision: 3, Scale: 1, HasPrecisionScale: true, Nullable: false, HasNullable: true}},
+		Rows:    rows([]interface{}{"2.0"}),
+	}
+	other := &ResultSet{
+		Columns: []string{"1 + 1.0"},
+		Types:   []ColumnType{{Type: "DOUBLE", Nullable: false, HasNullable: true}},
+		Rows:    rows([]interface{}{"2.0"}),
+	}
+	if r := Compare(ref, other, nil); !r.Equal() {
+		t.Fatal("expected metadata to be ignored")
+	}
+	r := Compare(ref, other, &Options{Metadata: true})
+	expected := []ColumnDiff{{Column: 0, Name: "1 + 1.0", Attribute: "type", Ref: "DECIMAL", Other: "DOUBLE"}}
+	if !reflect.DeepEqual(r.Metadata, expected) || r.Equal() {
+		t.Fatalf("expected %v, got %v", expected, r.Metadata)
+	}
+}
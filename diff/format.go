@@ -0,0 +1,51 @@
+package diff
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Unified renders the diff in the unified format, the rows only in the reference
+// are prefixed with `-` and the rows only in the compared result set with `+`.
+// A changed row is rendered as a removed row followed by an added row, and the
+// differences of column metadata are prefixed with `!`.
+func Unified(ref, other *ResultSet, r *Result) string {
+	var lines []string
+	for _, d := range r.Metadata {
+		lines = append(lines, "! "+d.String())
+	}
+	if len(r.Columns) > 0 {
+		lines = append(lines, "- "+strings.Join(ref.Columns, " | "), "+ "+strings.Join(other.Columns, " | "))
+	} else {
+		lines = append(lines, "  "+strings.Join(ref.Columns, " | "))
+	}
+	for _, row := range r.Rows {
+		switch row.Kind {
+		case Equal:
+			lines = append(lines, "  "+formatRow(ref.Rows[row.Ref]))
+		case Missing:
+			lines = append(lines, "- "+formatRow(ref.Rows[row.Ref]))
+		case Extra:
+			lines = append(lines, "+ "+formatRow(other.Rows[row.Other]))
+		case Changed:
+			lines = append(lines, "- "+formatRow(ref.Rows[row.Ref]), "+ "+formatRow(other.Rows[row.Other]))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func formatRow(row []sql.NullString) string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = FormatCell(cell)
+	}
+	return strings.Join(cells, " | ")
+}
+
+// FormatCell renders a cell like the mysql client, SQL NULL is rendered as `NULL`.
+func FormatCell(cell sql.NullString) string {
+	if !cell.Valid {
+		return "NULL"
+	}
+	return cell.String
+}
@@ -0,0 +1,65 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ColumnType is the type of a column reported by the server. The optional
+// attributes are only compared if both sides report them.
+type ColumnType struct {
+	// Type is the database type name, e.g. DECIMAL and VARCHAR.
+	Type string `json:"type"`
+	// Length is the length of variable-length types.
+	Length    int64 `json:"length,omitempty"`
+	HasLength bool  `json:"has_length,omitempty"`
+	// Precision and Scale are the precision and scale of DECIMAL, float and time types.
+	Precision         int64 `json:"precision,omitempty"`
+	Scale             int64 `json:"scale,omitempty"`
+	HasPrecisionScale bool  `json:"has_precision_scale,omitempty"`
+	Nullable          bool  `json:"nullable,omitempty"`
+	HasNullable       bool  `json:"has_nullable,omitempty"`
+}
+
+// ColumnDiff is a difference of an attribute of the column metadata.
+type ColumnDiff struct {
+	Column int    `json:"column"`
+	Name   string `json:"name"`
+	// Attribute is one of type, length, precision, scale and nullable.
+	Attribute string `json:"attribute"`
+	Ref       string `json:"ref"`
+	Other     string `json:"other"`
+}
+
+func (d ColumnDiff) String() string {
+	return fmt.Sprintf("column %s %s %s vs %s", d.Name, d.Attribute, d.Ref, d.Other)
+}
+
+// compareMetadata compares the metadata of the columns existing in both sides.
+func compareMetadata(ref, other *ResultSet) []ColumnDiff {
+	var diffs []ColumnDiff
+	for i := 0; i < len(ref.Types) && i < len(other.Types); i++ {
+		a, b := ref.Types[i], other.Types[i]
+		add := func(attribute, x, y string) {
+			if x != y {
+				name := ""
+				if i < len(ref.Columns) {
+					name = ref.Columns[i]
+				}
+				diffs = append(diffs, ColumnDiff{Column: i, Name: name, Attribute: attribute, Ref: x, Other: y})
+			}
+		}
+		add("type", a.Type, b.Type)
+		if a.HasLength && b.HasLength {
+			add("length", strconv.FormatInt(a.Length, 10), strconv.FormatInt(b.Length, 10))
+		}
+		if a.HasPrecisionScale && b.HasPrecisionScale {
+			add("precision", strconv.FormatInt(a.Precision, 10), strconv.FormatInt(b.Precision, 10))
+			add("scale", strconv.FormatInt(a.Scale, 10), strconv.FormatInt(b.Scale, 10))
+		}
+		if a.HasNullable && b.HasNullable {
+			add("nullable", strconv.FormatBool(a.Nullable), strconv.FormatBool(b.Nullable))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,123 @@
+package diff
+
+import (
+	"database/sql"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Rules are the normalization rules applied to the cells according to the column
+// types before comparing, which make the values considered equal if they are
+// different in some insignificant ways.
+type Rules struct {
+	// FloatAbsEpsilon and FloatRelEpsilon are the absolute and relative tolerances
+	// of FLOAT and DOUBLE values, two values are equal if either is satisfied.
+	FloatAbsEpsilon float64
+	FloatRelEpsilon float64
+	// DecimalScale rounds DECIMAL values to the number of fractional digits if non-negative.
+	DecimalScale int
+	// TrimDecimal trims the trailing fractional zeros of DECIMAL values, e.g. 1.500 to 1.5.
+	TrimDecimal bool
+	// TimePrecision truncates the fractional seconds of DATETIME, TIMESTAMP and TIME
+	// values to the number of digits if non-negative.
+	TimePrecision int
+	// IgnoreCase folds the case of string values, e.g. for case-insensitive collations.
+	IgnoreCase bool
+}
+
+// NewRules returns the rules which normalize nothing.
+func NewRules() *Rules {
+	return &Rules{DecimalScale: -1, TimePrecision: -1}
+}
+
+const (
+	floatFamily   = "float"
+	decimalFamily = "decimal"
+	timeFamily    = "time"
+	stringFamily  = "string"
+)
+
+// family returns the type family the rules are applied to.
+func family(t string) string {
+	switch strings.TrimPrefix(strings.ToUpper(t), "UNSIGNED ") {
+	case "FLOAT", "DOUBLE", "REAL":
+		return floatFamily
+	case "DECIMAL", "NEWDECIMAL":
+		return decimalFamily
+	case "DATETIME", "TIMESTAMP", "TIME":
+		return timeFamily
+	case "CHAR", "VARCHAR", "TEXT", "TINYTEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET":
+		return stringFamily
+	}
+	return ""
+}
+
+func columnFamily(types []ColumnType, col int) string {
+	if col >= len(types) {
+		return ""
+	}
+	return family(types[col].Type)
+}
+
+// normalize returns a copy of the result set with normalized cells.
+func (r *Rules) normalize(rs *ResultSet) *ResultSet {
+	normalized := &ResultSet{Columns: rs.Columns, Types: rs.Types, Rows: make([][]sql.NullString, len(rs.Rows))}
+	families := make([]string, len(rs.Columns))
+	for i := range families {
+		families[i] = columnFamily(rs.Types, i)
+	}
+	for i, row := range rs.Rows {
+		cells := make([]sql.NullString, len(row))
+		for j, cell := range row {
+			cells[j] = cell
+			if cell.Valid && j < len(families) {
+				cells[j].String = r.normalizeValue(families[j], cell.String)
+			}
+		}
+		normalized.Rows[i] = cells
+	}
+	return normalized
+}
+
+func (r *Rules) normalizeValue(family, value string) string {
+	switch family {
+	case decimalFamily:
+		if r.DecimalScale >= 0 {
+			if rat, ok := new(big.Rat).SetString(value); ok {
+				value = rat.FloatString(r.DecimalScale)
+			}
+		}
+		if r.TrimDecimal && strings.Contains(value, ".") {
+			value = strings.TrimRight(strings.TrimRight(value, "0"), ".")
+		}
+	case timeFamily:
+		if r.TimePrecision < 0 {
+			break
+		}
+		if dot := strings.LastIndex(value, "."); dot >= 0 {
+			end := dot + 1 + r.TimePrecision
+			if end > len(value) {
+				end = len(value)
+			}
+			value = strings.TrimRight(strings.TrimRight(value[:end], "0"), ".")
+		}
+	case stringFamily:
+		if r.IgnoreCase {
+			value = strings.ToLower(value)
+		}
+	}
+	return value
+}
+
+// equalFloat reports whether two float values are equal within the tolerances.
+func (r *Rules) equalFloat(a, b string) bool {
+	x, errx := strconv.ParseFloat(a, 64)
+	y, erry := strconv.ParseFloat(b, 64)
+	if errx != nil || erry != nil {
+		return a == b
+	}
+	delta := math.Abs(x - y)
+	return x == y || delta <= r.FloatAbsEpsilon || delta <= r.FloatRelEpsilon*math.Max(math.Abs(x), math.Abs(y))
+}
@@ -0,0 +1,131 @@
+package directive
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// null is the NULL returned by nullable, which is printed as NULL by
+// templates and kept unquoted by quote.
+type null struct{}
+
+func (null) String() string {
+	return "NULL"
+}
+
+// quote renders the value as a string literal unless it's NULL, so that the
+// values of nullable can be used in the place of strings.
+func quote(v interface{}) string {
+	if _, ok := v.(null); ok || v == nil {
+		return "NULL"
+	}
+	s := fmt.Sprint(v)
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func (g *generator) chance(p float64) bool {
+	return g.rand.Float64() < p
+}
+
+func (g *generator) nullable(p float64, v interface{}) interface{} {
+	if g.chance(p) {
+		return null{}
+	}
+	return v
+}
+
+func (g *generator) maybe(p float64, a, b interface{}) interface{} {
+	if g.chance(p) {
+		return a
+	}
+	return b
+}
+
+// edges are the boundary values of the types as SQL literals, which are
+// where the backends tend to differ. NULL is included in all of them.
+var edges = map[string][]string{
+	"tinyint":   {"0", "-0", "1", "-1", "127", "-128"},
+	"smallint":  {"0", "-0", "1", "-1", "32767", "-32768"},
+	"mediumint": {"0", "-0", "1", "-1", "8388607", "-8388608"},
+	"int":       {"0", "-0", "1", "-1", "2147483647", "-2147483648"},
+	"bigint":    {"0", "-0", "1", "-1", "9223372036854775807", "-9223372036854775808"},
+
+	"tinyint unsigned":   {"0", "1", "255"},
+	"smallint unsigned":  {"0", "1", "65535"},
+	"mediumint unsigned": {"0", "1", "16777215"},
+	"int unsigned":       {"0", "1", "4294967295"},
+	"bigint unsigned":    {"0", "1", "9223372036854775808", "18446744073709551615"},
+
+	"decimal": {"0", "-0", "0.0", "-0.00", "1", "-1", "0.5", "-0.5",
+		strings.Repeat("9", 65), "-" + strings.Repeat("9", 65), "0." + strings.Repeat("0", 29) + "1"},
+	"float": append([]string{"-0e0"}, floatEdges...),
+
+	"char": {"''", "' '", "'  '", "'a'", "'a '", "' a'", "'a  '", "'0'", "'A'", "'NULL'"},
+	"json": {"'null'", "'{}'", "'[]'", "'\"\"'", "'0'", "'-0'", "'true'", "'9223372036854775807'",
+		"'18446744073709551616'", "'1e308'", "'{\"a\": null}'", "'[[]]'"},
+
+	"date": {"'0000-00-00'", "'0000-01-01'", "'1000-01-01'", "'9999-12-31'", "'1970-01-01'",
+		"'2000-02-29'", "'2001-02-29'"},
+	"datetime": {"'0000-00-00 00:00:00'", "'1000-01-01 00:00:00'", "'9999-12-31 23:59:59'",
+		"'9999-12-31 23:59:59.999999'", "'1970-01-01 00:00:01'", "'2038-01-19 03:14:07'",
+		"'2000-02-29 12:00:00.5'"},
+	"timestamp": {"'0000-00-00 00:00:00'", "'1970-01-01 00:00:01'", "'2038-01-19 03:14:07'",
+		"'2038-01-19 03:14:08'", "'1970-01-01 00:00:00'", "'2000-01-01 00:00:00.999999'"},
+	"time": {"'00:00:00'", "'-00:00:01'", "'838:59:59'", "'-838:59:59'", "'23:59:59.999999'",
+		"'24:00:00'"},
+	"year": {"0", "'0'", "'0000'", "1901", "2155", "1970", "69", "70"},
+}
+
+// aliases are the other names of the types sharing the same edges.
+var aliases = map[string]string{
+	"integer":   "int",
+	"bool":      "tinyint",
+	"boolean":   "tinyint",
+	"numeric":   "decimal",
+	"double":    "float",
+	"real":      "float",
+	"varchar":   "char",
+	"text":      "char",
+	"string":    "char",
+	"binary":    "char",
+	"varbinary": "char",
+	"blob":      "char",
+	"enum":      "char",
+	"set":       "char",
+}
+
+func edgesOf(kind string) ([]string, error) {
+	kind = strings.Join(strings.Fields(strings.ToLower(kind)), " ")
+	fields := strings.Fields(kind)
+	if len(fields) > 0 {
+		if alias, ok := aliases[fields[0]]; ok {
+			fields[0] = alias
+			kind = strings.Join(fields, " ")
+		}
+	}
+	values, ok := edges[kind]
+	if !ok {
+		var kinds []string
+		for k := range edges {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		return nil, fmt.Errorf("unknown type %q for edge values, expected one of %s", kind, strings.Join(kinds, ", "))
+	}
+	return values, nil
+}
+
+// edge returns a boundary value of the type as a SQL literal, or NULL.
+func (g *generator) edge(kind string) (string, error) {
+	values, err := edgesOf(kind)
+	if err != nil {
+		return "", err
+	}
+	i := g.rand.Intn(len(values) + 1)
+	if i == len(values) {
+		return "NULL", nil
+	}
+	return values[i], nil
+}
@@ -1,18 +1,61 @@
 package directive
 
 import (
+	"bytes"
 	"math/rand"
 	"reflect"
+	"strings"
+	"text/template"
 )
 
-var Functions = map[string]interface{}{
-	"count":   count,
-	"first":   first,
-	"head":    head,
-	"tail":    tail,
-	"int":     randint,
-	"char":    randnstring,
-	"varchar": randstring,
+// Functions returns the template functions, whose random values are drawn
+// from r so that a template renders the same with the same seed.
+func Functions(r *rand.Rand) map[string]interface{} {
+	g := &generator{rand: r}
+	return map[string]interface{}{
+		"count":   count,
+		"first":   first,
+		"head":    head,
+		"tail":    tail,
+		"int":     g.randint,
+		"char":    g.randnstring,
+		"varchar": g.randstring,
+
+		"decimal":   g.randdecimal,
+		"float":     g.randfloat,
+		"double":    g.randdouble,
+		"floatedge": g.floatedge,
+		"date":      g.randdate,
+		"datetime":  g.randdatetime,
+		"timestamp": g.randtimestamp,
+		"time":      g.randtime,
+		"year":      g.randyear,
+		"bool":      g.randbool,
+		"json":      g.randjson,
+		"enum":      g.randenum,
+		"set":       g.randset,
+		"hex":       g.randhex,
+		"uuid":      g.randuuid,
+		"unicode":   g.randunicode,
+
+		"nullable": g.nullable,
+		"maybe":    g.maybe,
+		"edge":     g.edge,
+		"quote":    quote,
+	}
+}
+
+// Render renders the template with the random values drawn from the seed.
+func Render(text string, seed int64) (string, error) {
+	temp, err := template.New("template").Funcs(Functions(rand.New(rand.NewSource(seed)))).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	out := bytes.Buffer{}
+	if err := temp.Execute(&out, nil); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
 }
 
 func count(n int) []int {
@@ -39,26 +82,36 @@ func tail(x int) bool {
 	return x > 0
 }
 
-func randint(min, max int) int {
+// generator generates the random values of a render.
+type generator struct {
+	rand *rand.Rand
+}
+
+func (g *generator) randint(min, max int) int {
 	if max <= min {
 		return min
 	}
-	return min + rand.Intn(max-min)
+	return min + g.rand.Intn(max-min)
 }
 
 var chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-func randnstring(n int) string {
+func (g *generator) randnstring(n int) string {
 	if n <= 0 {
 		return ""
 	}
 	result := make([]byte, n)
 	for i := range result {
-		result[i] = chars[rand.Intn(len(chars))]
+		result[i] = chars[g.rand.Intn(len(chars))]
 	}
 	return string(result)
 }
 
-func randstring(maxn int) string {
-	return randnstring(maxn/2 + rand.Intn(maxn/2))
+// randstring returns a string shorter than maxn of at least half the length,
+// which is empty if maxn is less than 2, e.g. for VARCHAR(0) columns.
+func (g *generator) randstring(maxn int) string {
+	if maxn < 2 {
+		return ""
+	}
+	return g.randnstring(maxn/2 + g.rand.Intn(maxn/2))
 }
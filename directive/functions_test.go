@@ -0,0 +1,108 @@
+package directive
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	text := "insert into t values {{range $i := count 5}}{{if tail $i}},{{end}}({{int 1 1000}}, '{{varchar 20}}'){{end}}"
+	a, err := Render(text, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Render(text, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatalf("expected the same seed to render the same:\n%s\n%s", a, b)
+	}
+	if c, _ := Render(text, 43); c == a {
+		t.Fatalf("expected another seed to render differently: %s", c)
+	}
+	if _, err := Render("{{nosuchfunction}}", 42); err == nil {
+		t.Fatal("expected undefined functions to fail")
+	}
+}
+
+func TestTypes(t *testing.T) {
+	patterns := map[string]string{
+		`{{decimal 5 2}}`:                                  `^-?(0|[1-9]\d{0,2})\.\d{2}$`,
+		`{{decimal 3 0}}`:                                  `^-?(0|[1-9]\d{0,2})$`,
+		`{{float -1 1}}`:                                   `^-?(0|0\.\d+|1|\d(\.\d+)?e-\d+)$`,
+		`{{double 100 200}}`:                               `^1\d\d(\.\d+)?$`,
+		`{{floatedge}}`:                                    `^-?\d(\.\d+)?(e[+-]\d+)?$`,
+		`{{date "2000-01-01" "2000-01-31"}}`:               `^2000-01-([0-2]\d|3[01])$`,
+		`{{datetime "2000-01-01" "2000-01-01 23:59:59"}}`:  `^2000-01-01 \d\d:\d\d:\d\d$`,
+		`{{timestamp "1000-01-01" "1970-01-01 00:00:01"}}`: `^1970-01-01 00:00:01$`,
+		`{{time "-838:59:59" "838:59:59"}}`:                `^-?\d{2,3}:[0-5]\d:[0-5]\d$`,
+		`{{year 1901 2155}}`:                               `^(19[0-9]\d|20\d\d|21[0-5]\d)$`,
+		`{{bool}}`:                                         `^(true|false)$`,
+		`{{enum "a" "b" "c"}}`:                             `^[abc]$`,
+		`{{set "a" "b" "c"}}`:                              `^(a?,?b?,?c?)$`,
+		`{{hex 4}}`:                                        `^[0-9a-f]{8}$`,
+		`{{uuid}}`:                                         `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		`{{unicode 5}}`:                                    `^.{5}$`,
+		`{{varchar 20}}`:                                   `^[a-zA-Z0-9]{10,19}$`,
+		`{{varchar 1}}`:                                    `^$`,
+		`{{varchar 0}}`:                                    `^$`,
+	}
+	for text, pattern := range patterns {
+		re := regexp.MustCompile(pattern)
+		for seed := int64(0); seed < 100; seed++ {
+			s, err := Render(text, seed)
+			if err != nil {
+				t.Fatalf("%s: %v", text, err)
+			}
+			if !re.MatchString(s) {
+				t.Fatalf("%s rendered %q of seed %d", text, s, seed)
+			}
+		}
+	}
+	for seed := int64(0); seed < 100; seed++ {
+		s, err := Render(`{{json 3}}`, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid([]byte(s)) {
+			t.Fatalf("invalid json %s of seed %d", s, seed)
+		}
+	}
+	for _, text := range []string{`{{decimal 2 3}}`, `{{date "2000-13-01" "2001-01-01"}}`, `{{time "1:00" "2:00:00"}}`, `{{enum}}`} {
+		if _, err := Render(text, 42); err == nil {
+			t.Fatalf("expected %s to fail", text)
+		}
+	}
+}
+
+func TestEdges(t *testing.T) {
+	rendered := map[string]bool{}
+	for seed := int64(0); seed < 200; seed++ {
+		s, err := Render(`{{nullable 0.5 (int 1 10)}} {{quote (nullable 0.5 (varchar 4))}} {{maybe 0.5 (edge "BIGINT") 5}}`, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range strings.Fields(s) {
+			rendered[v] = true
+		}
+	}
+	for _, v := range []string{"NULL", "9223372036854775807", "-9223372036854775808", "-0", "5"} {
+		if !rendered[v] {
+			t.Fatalf("expected %s to be rendered in %v", v, rendered)
+		}
+	}
+	for _, kind := range []string{"int unsigned", "varchar", "date", "double", "json", "year"} {
+		if _, err := edgesOf(kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Render(`{{edge "geometry"}}`, 42); err == nil {
+		t.Fatal("expected unknown types to fail")
+	}
+	if s, _ := Render(`{{nullable 0 (quote "it's")}} {{nullable 1 1}} {{quote (nullable 1 "a")}}`, 42); s != `'it''s' NULL NULL` {
+		t.Fatalf("unexpected %s", s)
+	}
+}
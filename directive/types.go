@@ -0,0 +1,302 @@
+package directive
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (g *generator) randdecimal(precision, scale int) (string, error) {
+	if precision <= 0 || scale < 0 || scale > precision {
+		return "", fmt.Errorf("invalid decimal(%d, %d)", precision, scale)
+	}
+	var b strings.Builder
+	if g.rand.Intn(2) == 0 {
+		b.WriteByte('-')
+	}
+	// The integral digits are of random length so that small values are as
+	// likely as the ones of the full precision
+	digits := g.rand.Intn(precision - scale + 1)
+	if digits == 0 {
+		b.WriteByte('0')
+	} else {
+		b.WriteByte(byte('1' + g.rand.Intn(9)))
+		for i := 1; i < digits; i++ {
+			b.WriteByte(byte('0' + g.rand.Intn(10)))
+		}
+	}
+	if scale > 0 {
+		b.WriteByte('.')
+		for i := 0; i < scale; i++ {
+			b.WriteByte(byte('0' + g.rand.Intn(10)))
+		}
+	}
+	return b.String(), nil
+}
+
+func (g *generator) randfloat(min, max float64) string {
+	return strconv.FormatFloat(float64(float32(g.uniform(min, max))), 'g', -1, 32)
+}
+
+func (g *generator) randdouble(min, max float64) string {
+	return strconv.FormatFloat(g.uniform(min, max), 'g', -1, 64)
+}
+
+func (g *generator) uniform(min, max float64) float64 {
+	if max <= min {
+		return min
+	}
+	// Interpolated so that max-min doesn't overflow for the whole range
+	f := g.rand.Float64()
+	return min*(1-f) + max*f
+}
+
+// floatEdges are the boundaries of float and double, which are rounded,
+// overflowed or printed differently by the backends.
+var floatEdges = []string{
+	"0", "1.1754944e-38", "-1.1754944e-38", "3.4028235e+38", "-3.4028235e+38", "1e-45",
+	"2.2250738585072014e-308", "-2.2250738585072014e-308", "1.7976931348623157e+308",
+	"-1.7976931348623157e+308", "5e-324", "0.1", "0.30000000000000004", "1e+15", "1e+16",
+}
+
+func (g *generator) floatedge() string {
+	return floatEdges[g.rand.Intn(len(floatEdges))]
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	datetimeLayout = "2006-01-02 15:04:05"
+)
+
+var (
+	timestampMin = time.Date(1970, 1, 1, 0, 0, 1, 0, time.UTC)
+	timestampMax = time.Date(2038, 1, 19, 3, 14, 7, 0, time.UTC)
+)
+
+// parseTime parses a date or datetime argument of the temporal functions.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{datetimeLayout, dateLayout} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date or datetime %q", s)
+}
+
+// between returns a random time within [min, max] truncated to the unit.
+func (g *generator) between(min, max string, unit time.Duration) (time.Time, error) {
+	from, err := parseTime(min)
+	if err != nil {
+		return time.Time{}, err
+	}
+	to, err := parseTime(max)
+	if err != nil {
+		return time.Time{}, err
+	}
+	units := int64(to.Sub(from) / unit)
+	if units <= 0 {
+		return from, nil
+	}
+	return from.Add(time.Duration(g.rand.Int63n(units+1)) * unit), nil
+}
+
+func (g *generator) randdate(min, max string) (string, error) {
+	t, err := g.between(min, max, 24*time.Hour)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(dateLayout), nil
+}
+
+func (g *generator) randdatetime(min, max string) (string, error) {
+	t, err := g.between(min, max, time.Second)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(datetimeLayout), nil
+}
+
+// randtimestamp is randdatetime limited to the range of timestamp columns.
+func (g *generator) randtimestamp(min, max string) (string, error) {
+	from, err := parseTime(min)
+	if err != nil {
+		return "", err
+	}
+	to, err := parseTime(max)
+	if err != nil {
+		return "", err
+	}
+	if from.Before(timestampMin) {
+		from = timestampMin
+	}
+	if to.After(timestampMax) {
+		to = timestampMax
+	}
+	return g.randdatetime(from.Format(datetimeLayout), to.Format(datetimeLayout))
+}
+
+// parseDuration parses a time argument like '-838:59:59' to seconds.
+func parseDuration(s string) (int64, error) {
+	negative := strings.HasPrefix(s, "-")
+	parts := strings.Split(strings.TrimPrefix(s, "-"), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	var seconds int64
+	for _, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		seconds = seconds*60 + n
+	}
+	if negative {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+func (g *generator) randtime(min, max string) (string, error) {
+	from, err := parseDuration(min)
+	if err != nil {
+		return "", err
+	}
+	to, err := parseDuration(max)
+	if err != nil {
+		return "", err
+	}
+	seconds := from
+	if to > from {
+		seconds += g.rand.Int63n(to - from + 1)
+	}
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d:%02d:%02d", sign, seconds/3600, seconds/60%60, seconds%60), nil
+}
+
+func (g *generator) randyear(min, max int) int {
+	return g.randint(min, max+1)
+}
+
+func (g *generator) randbool() bool {
+	return g.rand.Intn(2) == 0
+}
+
+// randjson returns a random JSON document nested up to depth levels, whose
+// strings are alphanumeric so that it can be quoted as a string literal.
+func (g *generator) randjson(depth int) string {
+	var b strings.Builder
+	g.writeJSON(&b, depth)
+	return b.String()
+}
+
+func (g *generator) writeJSON(b *strings.Builder, depth int) {
+	kind := 6
+	if depth > 0 {
+		kind = 8
+	}
+	switch g.rand.Intn(kind) {
+	case 0:
+		b.WriteString("null")
+	case 1:
+		b.WriteString(strconv.FormatBool(g.randbool()))
+	case 2:
+		b.WriteString(strconv.Itoa(g.randint(-1000, 1000)))
+	case 3:
+		b.WriteString(g.randdouble(-1000, 1000))
+	case 4, 5:
+		fmt.Fprintf(b, "%q", g.randstring(10))
+	case 6:
+		b.WriteByte('[')
+		for i, n := 0, g.rand.Intn(4); i < n; i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			g.writeJSON(b, depth-1)
+		}
+		b.WriteByte(']')
+	case 7:
+		b.WriteByte('{')
+		for i, n := 0, g.rand.Intn(4); i < n; i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			// The keys are distinct since duplicated keys are merged differently
+			fmt.Fprintf(b, "\"k%d\": ", i)
+			g.writeJSON(b, depth-1)
+		}
+		b.WriteByte('}')
+	}
+}
+
+func (g *generator) randenum(values ...string) (string, error) {
+	if len(values) == 0 {
+		return "", errors.New("enum requires at least one value")
+	}
+	return values[g.rand.Intn(len(values))], nil
+}
+
+// randset returns a random subset of the values joined by commas in the
+// order of the values, which is how the backends store sets.
+func (g *generator) randset(values ...string) string {
+	var picked []string
+	for _, v := range values {
+		if g.randbool() {
+			picked = append(picked, v)
+		}
+	}
+	return strings.Join(picked, ",")
+}
+
+func (g *generator) randhex(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	bytes := make([]byte, n)
+	g.rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+func (g *generator) randuuid() string {
+	var u [16]byte
+	g.rand.Read(u[:])
+	// Version 4 and the RFC 4122 variant
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// scripts are the ranges of the characters of unicode strings, including
+// the emoji out of the basic multilingual plane which require utf8mb4.
+var scripts = [][2]rune{
+	{'a', 'z'},
+	{0x00c0, 0x00ff},   // Latin-1
+	{0x03b1, 0x03c9},   // Greek
+	{0x0410, 0x044f},   // Cyrillic
+	{0x05d0, 0x05ea},   // Hebrew
+	{0x0627, 0x064a},   // Arabic
+	{0x0905, 0x0939},   // Devanagari
+	{0x3041, 0x3096},   // Hiragana
+	{0x4e00, 0x9fa5},   // CJK
+	{0xac00, 0xd7a3},   // Hangul
+	{0x1f600, 0x1f64f}, // Emoticons
+	{0x1f300, 0x1f5ff}, // Pictographs
+}
+
+func (g *generator) randunicode(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	result := make([]rune, n)
+	for i := range result {
+		r := scripts[g.rand.Intn(len(scripts))]
+		result[i] = r[0] + rune(g.rand.Intn(int(r[1]-r[0]+1)))
+	}
+	return string(result)
+}
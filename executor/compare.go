@@ -0,0 +1,300 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidiff/diff"
+)
+
+// Comparison is the outcome of comparing the results of all backends for a
+// statement with the reference backend.
+type Comparison struct {
+	Results   []*QueryResult
+	Reference int
+	// Verdicts describe the differences with the reference briefly, including
+	// the ones which can't be highlighted in the contents, e.g. how the errors
+	// compare if either side failed, or NULL against the string 'NULL'.
+	Verdicts []string
+	// Diffs and WarningDiffs are the structured diffs of the result sets and the
+	// warnings with the reference. They are nil for the reference and the backends
+	// which can't be compared by content because either side failed.
+	Diffs        []*diff.Result
+	WarningDiffs []*diff.Result
+	// StatisticsDiffer reports whether the statistics of each backend differ
+	// from the reference after the hints are applied, e.g. the warnings count
+	// isn't compared with `ignore_warnings`.
+	StatisticsDiffer []bool
+	// Differs reports whether each backend differs from the reference.
+	Differs []bool
+	// SuppressedBy are the names of the suppression rules which suppressed the
+	// differences of each backend, empty if not suppressed.
+	SuppressedBy []string
+	// Unordered reports whether the order of rows is ignored.
+	Unordered  bool
+	Diff       bool
+	Suppressed bool
+}
+
+// Compare compares the results of all backends with the reference backend. The
+// result sets and the warnings are compared row by row and cell by cell, and
+// the summaries (e.g. affected rows, last insert id and warnings count) are
+// compared as a whole. If any side of a pair failed, the errors are compared by
+// error code and SQLSTATE instead. The order of rows is ignored according to
+// the order mode and the hints, and the cells are normalized by the rules of
+// the executor. The column metadata is compared if the executor requires.
+func (e *Executor) Compare(results []*QueryResult) *Comparison {
+	return e.CompareTo(results, e.reference)
+}
+
+// CompareTo compares the results with results[reference] like Compare, which
+// allows the reference results from elsewhere, e.g. a recorded baseline.
+func (e *Executor) CompareTo(results []*QueryResult, reference int) *Comparison {
+	n := len(results)
+	c := &Comparison{
+		Results:          results,
+		Reference:        reference,
+		Verdicts:         make([]string, n),
+		Diffs:            make([]*diff.Result, n),
+		WarningDiffs:     make([]*diff.Result, n),
+		StatisticsDiffer: make([]bool, n),
+		Differs:          make([]bool, n),
+		SuppressedBy:     make([]string, n),
+	}
+	ref := results[reference]
+	c.Unordered = e.unordered(ref.Rendered)
+	skip := skipsOf(ref.Rendered)
+	opts := &diff.Options{Unordered: c.Unordered, Rules: e.Rules, Metadata: e.CompareMetadata}
+	for i, result := range results {
+		if i == reference {
+			continue
+		}
+		if result.Error != nil || ref.Error != nil {
+			var match bool
+			c.Verdicts[i], match = e.errorVerdict(ref, result)
+			c.Differs[i] = !match
+			c.suppress(i, e.suppressed(ref, result, opts, skip))
+			continue
+		}
+
+		refRows, rows := skip.resultSets(ref, result)
+		d := diff.Compare(refRows, rows, opts)
+		refWarnings, warnings := skip.warningSets(ref, result)
+		wd := diff.Compare(refWarnings, warnings, nil)
+		c.Diffs[i], c.WarningDiffs[i] = d, wd
+		var verdicts []string
+		if !d.Equal() {
+			verdicts = append(verdicts, d.String())
+			for _, m := range d.Metadata {
+				verdicts = append(verdicts, m.String())
+			}
+			if verdict := nullVerdict(d, ref, result); verdict != "" {
+				verdicts = append(verdicts, verdict)
+			}
+		}
+		if !wd.Equal() {
+			verdicts = append(verdicts, "different warnings")
+		}
+		if !skip.sameStatistics(ref, result) {
+			c.StatisticsDiffer[i] = true
+			verdicts = append(verdicts, "different statistics")
+		}
+		c.Verdicts[i] = strings.Join(verdicts, "; ")
+		c.Differs[i] = len(verdicts) > 0
+		c.suppress(i, e.suppressed(ref, result, opts, skip))
+	}
+	return c
+}
+
+// suppressed returns the name of the first suppression rule which suppresses
+// the differences between result and the reference, or empty if none does.
+func (e *Executor) suppressed(ref, result *QueryResult, opts *diff.Options, skip skips) string {
+	for _, rule := range e.Suppressions.Find(ref.Rendered, result.Backend.Name) {
+		if rule.All() {
+			return rule.Name
+		}
+		if ref.Error != nil || result.Error != nil {
+			for _, err := range []error{ref.Error, result.Error} {
+				if me := serverError(err); me != nil && rule.IgnoreError(me.Number) {
+					return rule.Name
+				}
+			}
+			continue
+		}
+		if !skip.sameStatistics(ref, result) {
+			continue
+		}
+		refRows, rows := skip.resultSets(ref, result)
+		d := diff.Compare(rule.Apply(refRows), rule.Apply(rows), opts)
+		refWarnings, warnings := skip.warningSets(ref, result)
+		wd := diff.Compare(rule.Apply(refWarnings), rule.Apply(warnings), nil)
+		if d.Equal() && wd.Equal() {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// suppress marks the differences of the i-th backend suppressed by the rule,
+// the backend differs only if it isn't suppressed.
+func (c *Comparison) suppress(i int, rule string) {
+	if c.Differs[i] && rule != "" {
+		c.Differs[i] = false
+		c.SuppressedBy[i] = rule
+		c.Verdicts[i] += fmt.Sprintf(" (suppressed by %s)", rule)
+		c.Suppressed = true
+	}
+	c.Diff = c.Diff || c.Differs[i]
+}
+
+// Failed reports whether the statement failed in any backend.
+func (c *Comparison) Failed() bool {
+	for _, result := range c.Results {
+		if result.Error != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectErrors reports whether the statement failed in all backends with the
+// errors accepted by expected, e.g. the expected errors of a test file, which
+// makes the comparison match even if the errors differ from each other.
+func (c *Comparison) ExpectErrors(expected func(error) bool) bool {
+	for _, result := range c.Results {
+		if result.Error == nil || !expected(result.Error) {
+			return false
+		}
+	}
+	for i := range c.Results {
+		if c.Differs[i] {
+			c.Verdicts[i] += ", both expected"
+			c.Differs[i] = false
+		}
+	}
+	c.Diff = false
+	return true
+}
+
+// Render renders the result sets, statistics and warnings of all backends, the
+// differences are highlighted by del (parts of the reference) and ins (parts of
+// the compared backend). The reference is highlighted against the first backend
+// which differs from it.
+func (c *Comparison) Render(del, ins func(a ...interface{}) string) (contents, stats, warnings []string) {
+	n := len(c.Results)
+	contents, stats, warnings = make([]string, n), make([]string, n), make([]string, n)
+	ref := c.Results[c.Reference]
+	refMarks, refWarningMarks := marks{}, marks{}
+	var refMarked, summaryDiff bool
+	for i, result := range c.Results {
+		if i == c.Reference {
+			continue
+		}
+		if c.Diffs[i] == nil {
+			contents[i], stats[i], warnings[i] = result.Content(), result.Stat(), result.WarningsContent()
+			continue
+		}
+		rm, om := diffMarks(c.Diffs[i])
+		wrm, wom := diffMarks(c.WarningDiffs[i])
+		contents[i] = result.render(om, ins)
+		warnings[i] = result.renderWarnings(wom, ins)
+		summary := result.Summary()
+		if c.StatisticsDiffer[i] {
+			summary = ins(summary)
+			summaryDiff = true
+		}
+		stats[i] = fmt.Sprintf("%s (%.3f sec)", summary, result.duration.Seconds())
+		if c.Differs[i] && !refMarked {
+			refMarks, refWarningMarks, refMarked = rm, wrm, true
+		}
+	}
+
+	contents[c.Reference] = ref.render(refMarks, del)
+	warnings[c.Reference] = ref.renderWarnings(refWarningMarks, del)
+	stats[c.Reference] = ref.Stat()
+	if summaryDiff {
+		stats[c.Reference] = fmt.Sprintf("%s (%.3f sec)", del(ref.Summary()), ref.duration.Seconds())
+	}
+	return contents, stats, warnings
+}
+
+// diffMarks returns the cells to highlight in the reference and the compared result sets.
+func diffMarks(d *diff.Result) (marks, marks) {
+	ref, other := marks{}, marks{}
+	for _, col := range d.Columns {
+		ref.add(-1, col)
+		other.add(-1, col)
+	}
+	for _, m := range d.Metadata {
+		ref.add(-1, m.Column)
+		other.add(-1, m.Column)
+	}
+	for _, row := range d.Rows {
+		switch row.Kind {
+		case diff.Missing:
+			ref.add(row.Ref, -1)
+		case diff.Extra:
+			other.add(row.Other, -1)
+		case diff.Changed:
+			for _, col := range row.Cells {
+				ref.add(row.Ref, col)
+				other.add(row.Other, col)
+			}
+		}
+	}
+	return ref, other
+}
+
+// nullVerdict describes the first changed cell whose NULL-ness differs from the
+// reference, which is hard to tell from the rendered contents (e.g. NULL vs 'NULL').
+func nullVerdict(d *diff.Result, ref, result *QueryResult) string {
+	for _, row := range d.Rows {
+		for _, col := range row.Cells {
+			a, b := ref.Rows[row.Ref][col], result.Rows[row.Other][col]
+			if a.Valid == b.Valid {
+				continue
+			}
+			return fmt.Sprintf("row %d column %s: %s vs %s", row.Ref+1, ref.Columns[col], quoteCell(a.String, a.Valid), quoteCell(b.String, b.Valid))
+		}
+	}
+	return ""
+}
+
+// quoteCell renders a cell as a SQL literal to make NULL distinct from strings.
+func quoteCell(s string, valid bool) string {
+	if !valid {
+		return "NULL"
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Format renders the comparison like the mysql client, the results of every
+// backend follow the prompt `name(address)> query`. The rendered query is
+// displayed instead if the query starts with `!!`, and the seed of templates
+// is displayed before the results.
+func (c *Comparison) Format(query string, del, ins func(a ...interface{}) string) string {
+	ref := c.Results[c.Reference]
+	if strings.HasPrefix(query, "!!") {
+		query = ref.Rendered
+	}
+	contents, stats, warnings := c.Render(del, ins)
+	var b strings.Builder
+	if ref.Template {
+		fmt.Fprintf(&b, "-- seed: %d\n", ref.Seed)
+	}
+	for i, result := range c.Results {
+		fmt.Fprintf(&b, "%s(%s)> %s\n", result.Backend.Name, result.Backend.Config.Address(), query)
+		if contents[i] != "" {
+			b.WriteString(contents[i] + "\n")
+		}
+		b.WriteString(stats[i] + "\n")
+		if warnings[i] != "" {
+			b.WriteString(warnings[i] + "\n")
+		}
+		if c.Verdicts[i] != "" {
+			b.WriteString("-- " + c.Verdicts[i] + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidiff/suppress"
+)
+
+func TestSuppress(t *testing.T) {
+	a := NewBackend("MySQL", &Config{Host: "127.0.0.1", Port: 3306})
+	b := NewBackend("TiDB", &Config{Host: "127.0.0.1", Port: 4000})
+	e := NewExecutor(a, b)
+	rules, err := suppress.Parse([]byte(`[
+		{"name": "version", "match": "(?i)^select version\\(\\)"},
+		{"name": "clustered", "match": "(?i)^show create table", "patterns": [" ?/\\*T!\\[clustered_index\\] [A-Z]+ \\*/"]},
+		{"name": "unsupported", "fingerprint": "select sleep(1)", "errors": [1105]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Suppressions = rules
+	compare := func(query, x, y string, err error) *Comparison {
+		return e.Compare([]*QueryResult{
+			{Backend: a, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: x, Valid: true}}}},
+			{Backend: b, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: y, Valid: true}}}, Error: err},
+		})
+	}
+
+	c := compare("select version()", "8.0.11", "8.0.11-TiDB-v7.5.0", nil)
+	if c.Diff || !c.Suppressed || c.SuppressedBy[1] != "version" || c.Verdicts[1] != "1 changed rows (suppressed by version)" {
+		t.Fatalf("unexpected comparison %+v", c)
+	}
+	c = compare("show create table t", "PRIMARY KEY (`a`)", "PRIMARY KEY (`a`) /*T![clustered_index] CLUSTERED */", nil)
+	if c.Diff || !c.Suppressed {
+		t.Fatalf("expected the pattern to be suppressed %+v", c)
+	}
+	c = compare("show create table t", "KEY (`a`)", "PRIMARY KEY (`a`) /*T![clustered_index] CLUSTERED */", nil)
+	if !c.Diff || c.Suppressed || c.SuppressedBy[1] != "" {
+		t.Fatalf("expected other differences to be reported %+v", c)
+	}
+	c = compare("select sleep(2)", "0", "", &mysql.MySQLError{Number: 1105, Message: "unsupported"})
+	if c.Diff || c.SuppressedBy[1] != "unsupported" {
+		t.Fatalf("expected the error to be suppressed %+v", c)
+	}
+	c = compare("select sleep(2)", "0", "", &mysql.MySQLError{Number: 1064, Message: "syntax error"})
+	if !c.Diff || c.Suppressed {
+		t.Fatalf("expected other errors to be reported %+v", c)
+	}
+	c = compare("select version()", "8.0.11", "8.0.11", nil)
+	if c.Diff || c.Suppressed {
+		t.Fatalf("expected matched statements not to be suppressed %+v", c)
+	}
+}
+
+func TestWarningsError(t *testing.T) {
+	a := NewBackend("MySQL", &Config{Host: "127.0.0.1", Port: 3306})
+	b := NewBackend("TiDB", &Config{Host: "127.0.0.1", Port: 4000})
+	e := NewExecutor(a, b)
+	results := []*QueryResult{
+		{Backend: a, Rendered: "insert into t values (1)", AffectedRows: 1, Warnings: []Warning{{Level: "Note", Code: 1, Message: "x"}}},
+		{Backend: b, Rendered: "insert into t values (1)", AffectedRows: 1, WarningsError: errors.New("unsupported")},
+	}
+	c := e.Compare(results)
+	if c.Diff || c.Verdicts[1] != "" {
+		t.Fatalf("expected the failure of fetching warnings not to differ %+v", c)
+	}
+	if content := results[1].WarningsContent(); content != "SHOW WARNINGS failed: unsupported" {
+		t.Fatalf("unexpected warnings %q", content)
+	}
+}
+
+func TestWarningCountError(t *testing.T) {
+	a := NewBackend("MySQL", &Config{Host: "127.0.0.1", Port: 3306})
+	b := NewBackend("TiDB", &Config{Host: "127.0.0.1", Port: 4000})
+	e := NewExecutor(a, b)
+	results := []*QueryResult{
+		{Backend: a, Rendered: "insert into t values (1)", AffectedRows: 1, WarningCount: 1},
+		{Backend: b, Rendered: "insert into t values (1)", AffectedRows: 1, WarningCountError: errors.New("unsupported")},
+	}
+	if c := e.Compare(results); c.Diff {
+		t.Fatalf("expected the unavailable warning count not to differ %+v", c.Verdicts)
+	}
+	if content := results[1].WarningsContent(); content != "SHOW COUNT(*) WARNINGS failed: unsupported" {
+		t.Fatalf("unexpected warnings %q", content)
+	}
+	// The other statistics are still compared
+	results[1].AffectedRows = 2
+	if c := e.Compare(results); c.Verdicts[1] != "different statistics" {
+		t.Fatalf("unexpected verdicts %+v", c.Verdicts)
+	}
+}
+
+func TestIgnoreHints(t *testing.T) {
+	a := NewBackend("MySQL", &Config{Host: "127.0.0.1", Port: 3306})
+	b := NewBackend("TiDB", &Config{Host: "127.0.0.1", Port: 4000})
+	e := NewExecutor(a, b)
+	compare := func(query string) *Comparison {
+		return e.Compare([]*QueryResult{
+			{Backend: a, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: "1", Valid: true}}}},
+			{Backend: b, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: "1", Valid: true}}},
+				WarningCount: 1, Warnings: []Warning{{Level: "Note", Code: 1, Message: "x"}}},
+		})
+	}
+	if c := compare("select c from t"); !c.Diff || c.Verdicts[1] != "different warnings; different statistics" {
+		t.Fatalf("unexpected comparison %+v", c.Verdicts)
+	}
+	if c := compare("/* tidiff:ignore_warnings */ select c from t"); c.Diff || c.StatisticsDiffer[1] {
+		t.Fatalf("expected the warnings to be ignored %+v", c.Verdicts)
+	}
+	// The statistics are highlighted by the verdicts, not by the different summaries
+	mark := func(a ...interface{}) string { return "*" + fmt.Sprint(a...) + "*" }
+	for query, marked := range map[string]bool{"select c from t": true, "/* tidiff:ignore_warnings */ select c from t": false} {
+		_, stats, _ := compare(query).Render(mark, mark)
+		for i, stat := range stats {
+			if strings.HasPrefix(stat, "*") != marked {
+				t.Fatalf("unexpected statistics %d %q of %s", i, stat, query)
+			}
+		}
+	}
+	rows := func(query string) *Comparison {
+		return e.Compare([]*QueryResult{
+			{Backend: a, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: "1", Valid: true}}}},
+			{Backend: b, Rendered: query, Columns: []string{"c"}},
+		})
+	}
+	if c := rows("select c from t"); c.Verdicts[1] != "1 missing rows; different statistics" {
+		t.Fatalf("unexpected comparison %+v", c.Verdicts)
+	}
+	if c := rows("/* tidiff:ignore_rows */ select c from t"); c.Diff {
+		t.Fatalf("expected the rows to be ignored %+v", c.Verdicts)
+	}
+}
+
+func TestExpectErrors(t *testing.T) {
+	a := NewBackend("MySQL", &Config{Host: "127.0.0.1", Port: 3306})
+	b := NewBackend("TiDB", &Config{Host: "127.0.0.1", Port: 4000})
+	e := NewExecutor(a, b)
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	syntax := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	compare := func(err error) *Comparison {
+		return e.Compare([]*QueryResult{{Backend: a, Error: duplicate}, {Backend: b, Error: err}})
+	}
+	is := func(codes ...uint16) func(error) bool {
+		return func(err error) bool {
+			for _, code := range codes {
+				if serverError(err).Number == code {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	c := compare(syntax)
+	if !c.ExpectErrors(is(1062, 1064)) || c.Diff || c.Differs[1] || c.Verdicts[1] != "MySQL error 1062 vs TiDB error 1064, both expected" {
+		t.Fatalf("expected the errors to be expected %+v", c.Verdicts)
+	}
+	if c := compare(syntax); c.ExpectErrors(is(1062)) || !c.Diff || !c.Differs[1] {
+		t.Fatalf("expected the unexpected error to differ %+v", c.Verdicts)
+	}
+	if c := compare(nil); c.ExpectErrors(func(error) bool { return true }) || !c.Diff {
+		t.Fatalf("expected the success to differ %+v", c.Verdicts)
+	}
+}
@@ -1,6 +1,12 @@
 package executor
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
 
 type Config struct {
 	Host     string
@@ -12,9 +18,80 @@ type Config struct {
 }
 
 func (c *Config) DSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", c.User, c.Password, c.Host, c.Port, c.DB, c.Options)
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?%s", c.User, c.Password, c.Address(), c.DB, c.Options)
 }
 
+// Address returns host:port, IPv6 hosts are enclosed in brackets.
 func (c *Config) Address() string {
-	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// ParseConfig parses a connection string in the form `[user[:password]@]host[:port][/db][?options]`.
+// The missing parts will be filled with the values in defaults. IPv6 hosts are
+// enclosed in brackets if followed by the port, e.g. `[::1]:4000`.
+func ParseConfig(spec string, defaults Config) (*Config, error) {
+	c, s := defaults, spec
+	if index := strings.Index(s, "?"); index >= 0 {
+		c.Options = s[index+1:]
+		s = s[:index]
+	}
+	if index := strings.LastIndex(s, "@"); index >= 0 {
+		auth := s[:index]
+		s = s[index+1:]
+		if i := strings.Index(auth, ":"); i >= 0 {
+			c.User, c.Password = auth[:i], auth[i+1:]
+		} else {
+			c.User = auth
+		}
+	}
+	if index := strings.Index(s, "/"); index >= 0 {
+		c.DB = s[index+1:]
+		s = s[:index]
+	}
+	host, port, err := splitHostPort(s)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %q", err, spec)
+	}
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			return nil, fmt.Errorf("invalid port in %q", spec)
+		}
+		c.Port = n
+	}
+	if host != "" {
+		c.Host = host
+	}
+	if c.Host == "" {
+		return nil, fmt.Errorf("missing host in %q", spec)
+	}
+	return &c, nil
+}
+
+// splitHostPort splits host[:port], the port is empty if missing. IPv6 hosts
+// are enclosed in brackets unless there is no port.
+func splitHostPort(s string) (string, string, error) {
+	if strings.HasPrefix(s, "[") {
+		index := strings.Index(s, "]")
+		if index < 0 {
+			return "", "", errors.New("missing ] of the IPv6 host")
+		}
+		host, rest := s[1:index], s[index+1:]
+		if rest == "" {
+			return host, "", nil
+		}
+		if !strings.HasPrefix(rest, ":") || rest == ":" {
+			return "", "", errors.New("invalid port")
+		}
+		return host, rest[1:], nil
+	}
+	// A bare IPv6 host has more than one colon
+	if strings.Count(s, ":") != 1 {
+		return s, "", nil
+	}
+	index := strings.Index(s, ":")
+	if index == len(s)-1 {
+		return "", "", errors.New("invalid port")
+	}
+	return s[:index], s[index+1:], nil
 }
@@ -0,0 +1,68 @@
+package executor
+
+import "testing"
+
+var defaults = Config{Host: "127.0.0.1", Port: 3306, User: "root", Options: "charset=utf8mb4"}
+
+func TestParseConfig(t *testing.T) {
+	cases := []struct {
+		spec     string
+		expected Config
+	}{
+		{"user:pw@host:4000/db?timeout=1s", Config{Host: "host", Port: 4000, User: "user", Password: "pw", DB: "db", Options: "timeout=1s"}},
+		{"host", Config{Host: "host", Port: 3306, User: "root", Options: "charset=utf8mb4"}},
+		{"user@host/db", Config{Host: "host", Port: 3306, User: "user", DB: "db", Options: "charset=utf8mb4"}},
+		{"user:p@ss:w@host", Config{Host: "host", Port: 3306, User: "user", Password: "p@ss:w", Options: "charset=utf8mb4"}},
+		{":4000", Config{Host: "127.0.0.1", Port: 4000, User: "root", Options: "charset=utf8mb4"}},
+		{"/db", Config{Host: "127.0.0.1", Port: 3306, User: "root", DB: "db", Options: "charset=utf8mb4"}},
+		{"[::1]:4000/db", Config{Host: "::1", Port: 4000, User: "root", DB: "db", Options: "charset=utf8mb4"}},
+		{"[fe80::1]", Config{Host: "fe80::1", Port: 3306, User: "root", Options: "charset=utf8mb4"}},
+		{"root@::1/db", Config{Host: "::1", Port: 3306, User: "root", DB: "db", Options: "charset=utf8mb4"}},
+	}
+	for _, c := range cases {
+		config, err := ParseConfig(c.spec, defaults)
+		if err != nil {
+			t.Errorf("ParseConfig(%q) failed: %v", c.spec, err)
+			continue
+		}
+		if *config != c.expected {
+			t.Errorf("ParseConfig(%q) = %+v, expected %+v", c.spec, *config, c.expected)
+		}
+	}
+	for _, spec := range []string{"host:port", "host:", "host:0", "host:65536", "[::1", "[::1]4000", "[::1]:", "user@:x"} {
+		if config, err := ParseConfig(spec, defaults); err == nil {
+			t.Errorf("expected ParseConfig(%q) to fail, got %+v", spec, *config)
+		}
+	}
+	if _, err := ParseConfig("/db", Config{}); err == nil {
+		t.Error("expected missing host to fail")
+	}
+}
+
+func TestAddress(t *testing.T) {
+	config, err := ParseConfig("u:p@[::1]:4000/db?x=1", defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Address() != "[::1]:4000" || config.DSN() != "u:p@tcp([::1]:4000)/db?x=1" {
+		t.Fatalf("unexpected address %s or DSN %s", config.Address(), config.DSN())
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	b, err := ParseBackend(" tidb2 = user@host:4001/test", defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Name != "tidb2" || b.Config.Host != "host" || b.Config.Port != 4001 || b.Config.User != "user" || b.Config.DB != "test" {
+		t.Fatalf("unexpected backend %s %+v", b.Name, *b.Config)
+	}
+	for _, spec := range []string{"host:4000", "=host", " =host", "name=host:x"} {
+		if _, err := ParseBackend(spec, defaults); err == nil {
+			t.Errorf("expected ParseBackend(%q) to fail", spec)
+		}
+	}
+	if b, err := ParseBackend("name=", defaults); err != nil || *b.Config != defaults {
+		t.Errorf("expected the defaults of an empty connection string, got %v %v", b, err)
+	}
+}
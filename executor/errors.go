@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// serverError unwraps the error returned by the server, it returns nil if the
+// error isn't returned by the server (e.g. network errors).
+func serverError(err error) *mysql.MySQLError {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me
+	}
+	return nil
+}
+
+// sameError reports whether two errors are equivalent. Server errors are equivalent
+// if they have the same error code and SQLSTATE, and the same message unless the
+// executor ignores error messages. Other errors are compared by the message.
+func (e *Executor) sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ma, mb := serverError(a), serverError(b)
+	if ma == nil || mb == nil {
+		return ma == nil && mb == nil && (e.IgnoreErrorMessage || a.Error() == b.Error())
+	}
+	if ma.Number != mb.Number || ma.SQLState != mb.SQLState {
+		return false
+	}
+	return e.IgnoreErrorMessage || ma.Message == mb.Message
+}
+
+// DescribeError returns a brief description of the execution status, e.g. `error 1062`.
+func DescribeError(err error) string {
+	if err == nil {
+		return "success"
+	}
+	if me := serverError(err); me != nil {
+		return fmt.Sprintf("error %d", me.Number)
+	}
+	return "error"
+}
+
+// errorVerdict describes how the execution status of result compares with the
+// reference when at least one of them failed, and reports whether they match.
+func (e *Executor) errorVerdict(ref, result *QueryResult) (string, bool) {
+	if e.sameError(ref.Error, result.Error) {
+		return fmt.Sprintf("both failed with %s", DescribeError(ref.Error)), true
+	}
+	verdict := fmt.Sprintf("%s %s vs %s %s", ref.Backend.Name, DescribeError(ref.Error),
+		result.Backend.Name, DescribeError(result.Error))
+	if ref.Error != nil && result.Error != nil && DescribeError(ref.Error) == DescribeError(result.Error) {
+		difference := "messages"
+		if ma, mb := serverError(ref.Error), serverError(result.Error); ma != nil && mb != nil && ma.SQLState != mb.SQLState {
+			difference = "SQLSTATE"
+		}
+		verdict = fmt.Sprintf("both failed with %s but different %s", DescribeError(ref.Error), difference)
+	}
+	return verdict, false
+}
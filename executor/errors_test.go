@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestErrorVerdict(t *testing.T) {
+	a := NewBackend("MySQL", &Config{Host: "127.0.0.1", Port: 3306})
+	b := NewBackend("TiDB", &Config{Host: "127.0.0.1", Port: 4000})
+	duplicate := &mysql.MySQLError{Number: 1062, SQLState: [5]byte{'2', '3', '0', '0', '0'}, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+	duplicateKey := &mysql.MySQLError{Number: 1062, SQLState: [5]byte{'2', '3', '0', '0', '0'}, Message: "Duplicate entry '1' for key 't.PRIMARY'"}
+	otherState := &mysql.MySQLError{Number: 1062, SQLState: [5]byte{'H', 'Y', '0', '0', '0'}, Message: duplicate.Message}
+	syntax := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	network := errors.New("invalid connection")
+
+	cases := []struct {
+		ref, result error
+		ignore      bool
+		verdict     string
+		match       bool
+	}{
+		{duplicate, duplicate, false, "both failed with error 1062", true},
+		{duplicate, duplicateKey, false, "both failed with error 1062 but different messages", false},
+		{duplicate, duplicateKey, true, "both failed with error 1062", true},
+		{duplicate, otherState, true, "both failed with error 1062 but different SQLSTATE", false},
+		{duplicate, syntax, true, "MySQL error 1062 vs TiDB error 1064", false},
+		{duplicate, nil, false, "MySQL error 1062 vs TiDB success", false},
+		{nil, syntax, false, "MySQL success vs TiDB error 1064", false},
+		{duplicate, network, true, "MySQL error 1062 vs TiDB error", false},
+		{network, duplicate, true, "MySQL error vs TiDB error 1062", false},
+		{network, errors.New("invalid connection"), false, "both failed with error", true},
+		{network, errors.New("bad connection"), false, "both failed with error but different messages", false},
+		{network, errors.New("bad connection"), true, "both failed with error", true},
+		// Wrapped server errors are still server errors
+		{duplicate, fmt.Errorf("exec: %w", duplicate), false, "both failed with error 1062", true},
+	}
+	for i, c := range cases {
+		e := &Executor{IgnoreErrorMessage: c.ignore}
+		verdict, match := e.errorVerdict(&QueryResult{Backend: a, Error: c.ref}, &QueryResult{Backend: b, Error: c.result})
+		if verdict != c.verdict || match != c.match {
+			t.Errorf("case %d: got %q %v, expected %q %v", i, verdict, match, c.verdict, c.match)
+		}
+	}
+}
+
+func TestSameError(t *testing.T) {
+	e := &Executor{}
+	if !e.sameError(nil, nil) || e.sameError(nil, errors.New("x")) || e.sameError(errors.New("x"), nil) {
+		t.Fatal("unexpected comparison of nil errors")
+	}
+}
@@ -1,47 +1,158 @@
 package executor
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync/atomic"
-	"text/template"
 	"time"
 
+	"github.com/pingcap/tidiff/diff"
 	"github.com/pingcap/tidiff/directive"
+	"github.com/pingcap/tidiff/suppress"
 )
 
 const DefaultRetryCnt = 1
 
+// Backend is a named database server which all statements will be sent to.
+// All statements are executed in a dedicated session (conn) so that session
+// states (e.g. USE, SET, transactions and user variables) are preserved
+// between statements.
+type Backend struct {
+	Name   string
+	Config *Config
+	db     *sql.DB
+	conn   *sql.Conn
+}
+
+func NewBackend(name string, config *Config) *Backend {
+	return &Backend{Name: name, Config: config}
+}
+
+// ParseBackend parses a backend specified in the form `name=[user[:password]@]host[:port][/db][?options]`.
+func ParseBackend(spec string, defaults Config) (*Backend, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return nil, fmt.Errorf("invalid backend %q, expect name=[user[:password]@]host[:port][/db][?options]", spec)
+	}
+	config, err := ParseConfig(strings.TrimSpace(parts[1]), defaults)
+	if err != nil {
+		return nil, err
+	}
+	return NewBackend(strings.TrimSpace(parts[0]), config), nil
+}
+
+// Executor sends the same statement to all backends, the results of the
+// reference backend are the baseline which others will be compared with.
 type Executor struct {
-	MySQLConfig *Config
-	TiDBConfig  *Config
-	mysql       *sql.DB
-	tidb        *sql.DB
-	started     int32
+	Backends []*Backend
+	// FetchWarnings indicates whether to fetch and compare `SHOW WARNINGS` after every statement.
+	FetchWarnings bool
+	// IgnoreErrorMessage indicates whether to compare errors by error code and SQLSTATE only.
+	IgnoreErrorMessage bool
+	// Order is how the order of rows is compared.
+	Order Order
+	// Rules normalize the cells before comparing, nil to compare the cells as they are.
+	Rules *diff.Rules
+	// CompareMetadata indicates whether to compare the column metadata of result sets.
+	CompareMetadata bool
+	// Seed is the seed of the random values of the first template, the seed of
+	// every following template is one greater.
+	Seed int64
+	// Suppressions are the known differences which are suppressed instead of reported.
+	Suppressions suppress.Rules
+	reference    int
+	started      int32
+	renders      int64
+}
+
+// NewExecutor returns an executor with the backends and the first one is the reference.
+func NewExecutor(backends ...*Backend) *Executor {
+	return &Executor{Backends: backends}
+}
+
+// SetReference marks the backend named name as the reference backend.
+func (e *Executor) SetReference(name string) error {
+	for i, b := range e.Backends {
+		if strings.EqualFold(b.Name, name) {
+			e.reference = i
+			return nil
+		}
+	}
+	return fmt.Errorf("reference backend %s not found", name)
 }
 
-func NewExecutor(mysql, tidb *Config) *Executor {
-	return &Executor{MySQLConfig: mysql, TiDBConfig: tidb}
+// Reference returns the index of the reference backend.
+func (e *Executor) Reference() int {
+	return e.reference
 }
 
 func (e *Executor) Open(retryCnt int) error {
+	if len(e.Backends) < 1 {
+		return errors.New("at least one backend is required")
+	}
 	if atomic.AddInt32(&e.started, 1) != 1 {
 		return errors.New("executor started")
 	}
-	mysql, err := openDBWithRetry("mysql", e.MySQLConfig.DSN(), retryCnt)
-	if err != nil {
-		return err
+	for _, b := range e.Backends {
+		db, err := openDBWithRetry("mysql", b.Config.DSN(), retryCnt)
+		if err != nil {
+			return err
+		}
+		b.db = db
+		if err := b.connect(); err != nil {
+			return err
+		}
 	}
-	tidb, err := openDBWithRetry("mysql", e.TiDBConfig.DSN(), retryCnt)
+	return nil
+}
+
+// Reconnect discards the sessions of all backends and starts new ones, all
+// session states will be reset.
+func (e *Executor) Reconnect() error {
+	for _, b := range e.Backends {
+		if err := b.connect(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the sessions and databases of all backends.
+func (e *Executor) Close() error {
+	var firstErr error
+	for _, b := range e.Backends {
+		if b.conn != nil {
+			if err := b.conn.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			b.conn = nil
+		}
+		if b.db != nil {
+			if err := b.db.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			b.db = nil
+		}
+	}
+	return firstErr
+}
+
+// connect pins a new session for the backend and closes the previous one.
+func (b *Backend) connect() error {
+	if b.conn != nil {
+		// The old session may be broken already, which is usually the reason to reconnect.
+		_ = b.conn.Close()
+		b.conn = nil
+	}
+	conn, err := b.db.Conn(context.Background())
 	if err != nil {
-		return err
+		return fmt.Errorf("connect to %s(%s) failed: %v", b.Name, b.Config.Address(), err)
 	}
-	e.mysql = mysql
-	e.tidb = tidb
+	b.conn = conn
 	return nil
 }
 
@@ -73,50 +184,177 @@ func openDBWithRetry(driverName, dataSourceName string, retryCnt int) (mdb *sql.
 	return
 }
 
-func q(ctx context.Context, db *sql.DB, query string, ch chan *QueryResult) {
+func q(ctx context.Context, b *Backend, query string, fetchWarnings bool, ch chan *QueryResult) {
 	go func() {
+		result := &QueryResult{Backend: b, Rendered: query}
 		start := time.Now()
-		rows, err := db.QueryContext(ctx, query)
-		duration := time.Since(start)
-		ch <- &QueryResult{Result: rows, Error: err, Rendered: query, duration: duration}
+		if returnsRows(query) {
+			result.Error = b.fetch(ctx, query, result)
+		} else {
+			result.Error = b.exec(ctx, query, result)
+		}
+		result.duration = time.Since(start)
+		if result.Error == nil && fetchWarnings {
+			result.Warnings, result.WarningsError = b.warnings(ctx)
+		}
+		if result.Error == nil {
+			// Some MySQL compatible servers don't support counting warnings, which
+			// doesn't fail the statement but skips comparing the number.
+			result.WarningCount, result.WarningCountError = b.warningCount(ctx)
+		}
+		ch <- result
 	}()
 }
 
-func (e *Executor) query(query string) (*QueryResult, *QueryResult) {
-	ctx := context.Background()
-	mysqlResultCh := make(chan *QueryResult)
-	tidbResultCh := make(chan *QueryResult)
+// fetch executes the query and reads the whole result set.
+func (b *Backend) fetch(ctx context.Context, query string, result *QueryResult) error {
+	rows, err := b.conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	result.Columns = cols
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		ct := diff.ColumnType{Type: t.DatabaseTypeName()}
+		ct.Length, ct.HasLength = t.Length()
+		ct.Precision, ct.Scale, ct.HasPrecisionScale = t.DecimalSize()
+		ct.Nullable, ct.HasNullable = t.Nullable()
+		result.ColumnTypes = append(result.ColumnTypes, ct)
+	}
+	for rows.Next() {
+		var columns = make([]sql.NullString, len(cols))
+		var pointer = make([]interface{}, len(cols))
+		for i := range columns {
+			pointer[i] = &columns[i]
+		}
+		if err := rows.Scan(pointer...); err != nil {
+			return err
+		}
+		result.Rows = append(result.Rows, columns)
+	}
+	return rows.Err()
+}
 
-	q(ctx, e.mysql, query, mysqlResultCh)
-	q(ctx, e.tidb, query, tidbResultCh)
+// exec executes the statement which doesn't return a result set.
+func (b *Backend) exec(ctx context.Context, query string, result *QueryResult) error {
+	res, err := b.conn.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	if result.AffectedRows, err = res.RowsAffected(); err != nil {
+		return err
+	}
+	result.LastInsertID, err = res.LastInsertId()
+	return err
+}
 
-	mysqlResult := <-mysqlResultCh
-	tidbResult := <-tidbResultCh
-	return mysqlResult, tidbResult
+// warnings returns the warnings generated by the last statement in the session.
+func (b *Backend) warnings(ctx context.Context) ([]Warning, error) {
+	rows, err := b.conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var warnings []Warning
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings, rows.Err()
 }
 
-func (e *Executor) Query(query string) (*QueryResult, *QueryResult, error) {
+// warningCount returns the number of warnings generated by the last statement in the session.
+func (b *Backend) warningCount(ctx context.Context) (int, error) {
+	var count int
+	err := b.conn.QueryRowContext(ctx, "SHOW COUNT(*) WARNINGS").Scan(&count)
+	return count, err
+}
+
+func (e *Executor) query(query string) []*QueryResult {
+	ctx := context.Background()
+	chs := make([]chan *QueryResult, len(e.Backends))
+	for i, b := range e.Backends {
+		chs[i] = make(chan *QueryResult)
+		q(ctx, b, query, e.FetchWarnings, chs[i])
+	}
+
+	results := make([]*QueryResult, len(e.Backends))
+	for i, ch := range chs {
+		results[i] = <-ch
+	}
+	return results
+}
+
+// Query executes the query in all backends and returns the results in the order of backends.
+// The query is a template if it starts with `!`, whose random values are drawn
+// from the seed in the `tidiff:seed` hint, or the next seed of the executor.
+func (e *Executor) Query(query string) ([]*QueryResult, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
-		return nil, nil, errors.New("empty query")
+		return nil, errors.New("empty query")
 	}
 
-	var mysqlResult, tidbResult *QueryResult
 	// Parse directive if query start with `!`
 	if len(query) > 1 && query[0] == '!' {
 		text := strings.TrimLeft(query, "!")
-		temp, err := template.New("template").Funcs(directive.Functions).Parse(text)
+		seed, err := e.seed(text)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+		if query, err = directive.Render(text, seed); err != nil {
+			return nil, err
 		}
-		out := bytes.Buffer{}
-		if err := temp.Execute(&out, nil); err != nil {
-			return nil, nil, err
+		results := e.query(query)
+		for _, result := range results {
+			result.Template, result.Seed = true, seed
 		}
-		text = strings.TrimSpace(out.String())
-		mysqlResult, tidbResult = e.query(text)
-	} else {
-		mysqlResult, tidbResult = e.query(query)
+		return results, nil
+	}
+	return e.query(query), nil
+}
+
+// seed returns the seed of the template, which is the seed in the hint if
+// any, or the seed of the executor plus the number of templates rendered.
+func (e *Executor) seed(text string) (int64, error) {
+	if s, ok := Hints(text)["seed"]; ok {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid seed %q", s)
+		}
+		return seed, nil
+	}
+	seed := e.Seed + e.renders
+	e.renders++
+	return seed, nil
+}
+
+// Renders returns the number of templates rendered with the seeds of the executor.
+func (e *Executor) Renders() int64 {
+	return e.renders
+}
+
+// WithSeed returns the template with the seed hint, which renders the same
+// as the query rendered with the seed. The query is returned as is if it
+// isn't a template or has the seed hint already.
+func WithSeed(query string, seed int64) string {
+	query = strings.TrimSpace(query)
+	if len(query) < 2 || query[0] != '!' {
+		return query
+	}
+	text := strings.TrimLeft(query, "!")
+	if _, ok := Hints(text)["seed"]; ok {
+		return query
 	}
-	return mysqlResult, tidbResult, nil
+	return fmt.Sprintf("%s/* %sseed=%d */ %s", query[:len(query)-len(text)], hintPrefix, seed, strings.TrimSpace(text))
 }
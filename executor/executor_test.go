@@ -0,0 +1,40 @@
+package executor
+
+import "testing"
+
+func TestSeed(t *testing.T) {
+	e := &Executor{Seed: 100}
+	for i, expected := range []int64{100, 101, 7, 102} {
+		text := "select {{int 1 10}}"
+		if i == 2 {
+			text = "/* tidiff:seed=7 */ " + text
+		}
+		seed, err := e.seed(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seed != expected {
+			t.Fatalf("expected seed %d, got %d", expected, seed)
+		}
+	}
+	if e.Renders() != 3 {
+		t.Fatalf("unexpected renders %d", e.Renders())
+	}
+	if _, err := e.seed("/* tidiff:seed=x */ select 1"); err == nil {
+		t.Fatal("expected invalid seed")
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	cases := map[string]string{
+		"select 1":               "select 1",
+		"!select {{int 1 10}}":   "!/* tidiff:seed=42 */ select {{int 1 10}}",
+		"!! select {{int 1 10}}": "!!/* tidiff:seed=42 */ select {{int 1 10}}",
+		"!/* tidiff:seed=7 */ select {{int 1 10}}": "!/* tidiff:seed=7 */ select {{int 1 10}}",
+	}
+	for query, expected := range cases {
+		if s := WithSeed(query, 42); s != expected {
+			t.Errorf("WithSeed(%q) = %q, expected %q", query, s, expected)
+		}
+	}
+}
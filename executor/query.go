@@ -3,61 +3,211 @@ package executor
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/pingcap/tidiff/diff"
 )
 
 type QueryResult struct {
-	Result   *sql.Rows
+	Backend  *Backend
 	Error    error
 	Rendered string
-	duration time.Duration
-	rowcount int
-	columns  int
+	// Template reports whether the query is a template, which is rendered with
+	// the random values drawn from Seed.
+	Template bool
+	Seed     int64
+	Columns  []string
+	// ColumnTypes are the column types reported by the server.
+	ColumnTypes []diff.ColumnType
+	// Rows are the result set, SQL NULL values are the cells with Valid false.
+	Rows [][]sql.NullString
+	// AffectedRows, LastInsertID are only available for statements which
+	// don't return a result set (e.g. DML and DDL statements).
+	AffectedRows int64
+	LastInsertID int64
+	// WarningCount is the number of warnings generated by the statement.
+	WarningCount int
+	// Warnings is the output of `SHOW WARNINGS` after the statement, which is
+	// only available if the executor fetches warnings.
+	Warnings []Warning
+	// WarningsError is the failure of fetching the warnings, which doesn't fail
+	// the statement but skips comparing the warnings.
+	WarningsError error
+	// WarningCountError is the failure of reading the number of warnings, which
+	// doesn't fail the statement but skips comparing the number of warnings.
+	WarningCountError error
+	duration          time.Duration
 }
 
-func (result *QueryResult) Stat() string {
+// Warning is a row of `SHOW WARNINGS`.
+type Warning struct {
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s (Code %d): %s", w.Level, w.Code, w.Message)
+}
+
+func plural(n int64, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// Summary returns the statistics of the result which is comparable between
+// backends, the execution duration is excluded.
+func (result *QueryResult) Summary() string {
 	if result.Error != nil {
 		return result.Error.Error()
 	}
-	if result.rowcount < 1 && result.columns < 1 {
-		return fmt.Sprintf("Query OK (%.3f sec)", result.duration.Seconds())
+	var summary string
+	if len(result.Columns) < 1 {
+		summary = "Query OK, " + plural(result.AffectedRows, "row") + " affected"
+		if result.LastInsertID != 0 {
+			summary += fmt.Sprintf(", last insert id %d", result.LastInsertID)
+		}
+	} else {
+		summary = plural(int64(len(result.Rows)), "row") + " in set"
+	}
+	if result.WarningCount > 0 {
+		summary += ", " + plural(int64(result.WarningCount), "warning")
 	}
-	return fmt.Sprintf("%d row in set (%.3f sec)", result.rowcount, result.duration.Seconds())
+	return summary
 }
 
-func (result *QueryResult) Content() string {
+func (result *QueryResult) Stat() string {
 	if result.Error != nil {
-		return ""
-	}
-	cols, err := result.Result.Columns()
-	if err != nil {
-		result.Error = err
-		return ""
+		return result.Error.Error()
 	}
-	result.columns = len(cols)
-	var allRows [][][]byte
-	for result.Result.Next() {
-		var columns = make([][]byte, len(cols))
-		var pointer = make([]interface{}, len(cols))
-		for i := range columns {
-			pointer[i] = &columns[i]
-		}
-		err := result.Result.Scan(pointer...)
-		if err != nil {
-			result.Error = err
-			return ""
+	return fmt.Sprintf("%s (%.3f sec)", result.Summary(), result.duration.Seconds())
+}
+
+// Duration returns the time used to execute the statement and read the result set.
+func (result *QueryResult) Duration() time.Duration {
+	return result.duration
+}
+
+// WarningsContent renders the warnings of the statement one per line.
+func (result *QueryResult) WarningsContent() string {
+	return result.renderWarnings(nil, nil)
+}
+
+// renderWarnings renders the warnings one per line, the warnings with any cell
+// in marks are decorated by decorate.
+func (result *QueryResult) renderWarnings(m marks, decorate func(a ...interface{}) string) string {
+	lines := make([]string, len(result.Warnings))
+	for i, w := range result.Warnings {
+		lines[i] = w.String()
+		if len(m[i]) > 0 {
+			lines[i] = decorate(lines[i])
 		}
-		allRows = append(allRows, columns)
-		result.rowcount++
 	}
-	if err := result.Result.Err(); err != nil {
-		result.Error = err
-		return ""
+	if result.WarningsError != nil {
+		lines = append(lines, fmt.Sprintf("SHOW WARNINGS failed: %v", result.WarningsError))
+	}
+	if result.WarningCountError != nil {
+		lines = append(lines, fmt.Sprintf("SHOW COUNT(*) WARNINGS failed: %v", result.WarningCountError))
 	}
-	if result.rowcount < 1 {
+	return strings.Join(lines, "\n")
+}
+
+// ResultSet returns the result set to compare.
+func (result *QueryResult) ResultSet() *diff.ResultSet {
+	return &diff.ResultSet{Columns: result.Columns, Types: result.ColumnTypes, Rows: result.Rows}
+}
+
+// skips are the parts of the results which aren't compared, which are skipped
+// by the hints `tidiff:ignore_rows` and `tidiff:ignore_warnings`.
+type skips struct {
+	rows     bool
+	warnings bool
+}
+
+// skipsOf returns the skipped parts of the results of the query.
+func skipsOf(query string) skips {
+	hints := Hints(query)
+	_, rows := hints["ignore_rows"]
+	_, warnings := hints["ignore_warnings"]
+	return skips{rows: rows, warnings: warnings}
+}
+
+// resultSets returns the result sets of both results to compare, which are
+// considered the same if the rows are skipped.
+func (s skips) resultSets(ref, result *QueryResult) (*diff.ResultSet, *diff.ResultSet) {
+	if s.rows {
+		return &diff.ResultSet{}, &diff.ResultSet{}
+	}
+	return ref.ResultSet(), result.ResultSet()
+}
+
+// sameStatistics reports whether both results have the same statistics, the
+// number of rows and warnings aren't compared if they are skipped, and the
+// number of warnings isn't either if it's unavailable in either.
+func (s skips) sameStatistics(ref, result *QueryResult) bool {
+	if s.rows {
+		return true
+	}
+	if s.warnings || ref.WarningCountError != nil || result.WarningCountError != nil {
+		a, b := *ref, *result
+		a.WarningCount, b.WarningCount = 0, 0
+		return a.Summary() == b.Summary()
+	}
+	return ref.Summary() == result.Summary()
+}
+
+// warningSets returns the warnings of both results to compare, which are
+// considered the same if the warnings are skipped or either failed to fetch.
+func (s skips) warningSets(ref, result *QueryResult) (*diff.ResultSet, *diff.ResultSet) {
+	if s.warnings || ref.WarningsError != nil || result.WarningsError != nil {
+		return &diff.ResultSet{}, &diff.ResultSet{}
+	}
+	return ref.warningSet(), result.warningSet()
+}
+
+// warningSet returns the warnings as a result set to compare.
+func (result *QueryResult) warningSet() *diff.ResultSet {
+	set := &diff.ResultSet{Columns: []string{"Level", "Code", "Message"}}
+	for _, w := range result.Warnings {
+		set.Rows = append(set.Rows, []sql.NullString{
+			{String: w.Level, Valid: true},
+			{String: strconv.Itoa(w.Code), Valid: true},
+			{String: w.Message, Valid: true},
+		})
+	}
+	return set
+}
+
+// marks are the cells to highlight indexed by row and column, the row -1 is the
+// header and the column -1 stands for all cells of the row.
+type marks map[int]map[int]bool
+
+func (m marks) add(row, col int) {
+	if m[row] == nil {
+		m[row] = map[int]bool{}
+	}
+	m[row][col] = true
+}
+
+func (m marks) has(row, col int) bool {
+	return m[row][col] || m[row][-1]
+}
+
+func (result *QueryResult) Content() string {
+	return result.render(nil, nil)
+}
+
+// render renders the result set as a table like the mysql client, the cells in
+// marks are decorated by decorate.
+func (result *QueryResult) render(m marks, decorate func(a ...interface{}) string) string {
+	if result.Error != nil || len(result.Rows) < 1 {
 		return ""
 	}
+	cols, allRows := result.Columns, result.Rows
 
 	// Calculate the max column length
 	var colLength []int
@@ -66,7 +216,7 @@ func (result *QueryResult) Content() string {
 	}
 	for _, row := range allRows {
 		for n, col := range row {
-			if l := len(col); colLength[n] < l {
+			if l := len(diff.FormatCell(col)); colLength[n] < l {
 				colLength[n] = l
 			}
 		}
@@ -82,16 +232,23 @@ func (result *QueryResult) Content() string {
 	var push = func(line string) {
 		lines = append(lines, line)
 	}
+	// cell pads the text and decorates it if marked, the padding is calculated before
+	// decorating so that the decorations don't break the alignment.
+	var cell = func(row, index int, text string) string {
+		padded := text + strings.Repeat(" ", colLength[index]-1-len(text))
+		if m.has(row, index) {
+			padded = decorate(padded)
+		}
+		return " " + padded + "|"
+	}
 
 	// Write table header
 	var header string
 	for index, col := range cols {
-		length := colLength[index]
-		padding := length - 1 - len(col)
 		if index == 0 {
 			header += "|"
 		}
-		header += " " + col + strings.Repeat(" ", padding) + "|"
+		header += cell(-1, index, col)
 	}
 	splitLine := "+" + strings.Repeat("-", total) + "+"
 	push(splitLine)
@@ -99,25 +256,16 @@ func (result *QueryResult) Content() string {
 	push(splitLine)
 
 	// Write rows data
-	for _, row := range allRows {
+	for n, row := range allRows {
 		var line string
 		for index, col := range row {
-			length := colLength[index]
-			padding := length - 1 - len(col)
 			if index == 0 {
 				line += "|"
 			}
-			line += " " + string(col) + strings.Repeat(" ", padding) + "|"
+			line += cell(n, index, diff.FormatCell(col))
 		}
 		push(line)
 	}
 	push(splitLine)
 	return strings.Join(lines, "\n")
 }
-
-func (result *QueryResult) Close() {
-	if result.Result == nil {
-		return
-	}
-	result.Result.Close()
-}
@@ -1,13 +1,9 @@
 package executor
 
 import (
-	"bytes"
 	"errors"
-	"fmt"
-	"strings"
 
 	"github.com/fatih/color"
-	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
 // DoDiff executes sqls in the driver configured in exec. If there is an inconsistent sql execution result,
@@ -29,56 +25,20 @@ func (exec *Executor) DoDiff(sqls []string, handleResultsFn func(strs []string))
 }
 
 func (exec *Executor) diffExecResult(query string, handleResultsFn func(str []string)) error {
-	mysqlResult, tidbResult, err := exec.Query(query)
+	results, err := exec.Query(query)
 	if err != nil {
 		return err
 	}
-	defer mysqlResult.Close()
-	defer tidbResult.Close()
-	mysqlContent, tidbContent := mysqlResult.Content(), tidbResult.Content()
-	if mysqlResult.Error == nil && tidbResult.Error == nil {
-		green := color.New(color.FgGreen).SprintFunc()
-		red := color.New(color.FgRed).SprintFunc()
-		patch := diffmatchpatch.New()
-		diff := patch.DiffMain(mysqlContent, tidbContent, false)
-		var newMySQLContent, newTiDBContent bytes.Buffer
-		for _, d := range diff {
-			switch d.Type {
-			case diffmatchpatch.DiffEqual:
-				newMySQLContent.WriteString(d.Text)
-				newTiDBContent.WriteString(d.Text)
-			case diffmatchpatch.DiffDelete:
-				newMySQLContent.WriteString(red(d.Text))
-			case diffmatchpatch.DiffInsert:
-				newTiDBContent.WriteString(green(d.Text))
-			}
-		}
-		mysqlContent = newMySQLContent.String()
-		tidbContent = newTiDBContent.String()
-	}
-
-	logQuery := query
-	if strings.HasPrefix(query, "!!") {
-		logQuery = mysqlResult.Rendered
-	}
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	c := exec.Compare(results)
 
 	if handleResultsFn != nil {
-		handleResultsFn([]string{mysqlContent, tidbContent})
+		contents, _, _ := c.Render(red, green)
+		handleResultsFn(contents)
 	}
-	if mysqlContent == tidbContent {
+	if !c.Diff {
 		return nil
 	}
-
-	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("MySQL(%s)> %s\n", exec.MySQLConfig.Address(), logQuery))
-	if mysqlContent != "" {
-		buf.WriteString(mysqlContent)
-	}
-	buf.WriteString(mysqlResult.Stat() + "\n")
-	buf.WriteString(fmt.Sprintf("TiDB(%s)> %s\n", exec.TiDBConfig.Address(), logQuery))
-	if tidbContent != "" {
-		buf.WriteString(tidbContent)
-	}
-	buf.WriteString(tidbResult.Stat() + "\n")
-	return errors.New(buf.String())
+	return errors.New(c.Format(query, red, green))
 }
@@ -23,11 +23,12 @@ var defaultTiDBDSNConfig = &Config{
 }
 
 func Example() {
-	exec := NewExecutor(defaultMySQLDSNConfig, defaultTiDBDSNConfig)
+	exec := NewExecutor(NewBackend("MySQL", defaultMySQLDSNConfig), NewBackend("TiDB", defaultTiDBDSNConfig))
 	if err := exec.Open(DefaultRetryCnt); err != nil {
 		fmt.Printf("open failed err %v", err)
 		return
 	}
+	defer exec.Close()
 
 	sqls := []string{"drop table t", "create table t(a int)", "show create table t", "drop table t"}
 	if err := exec.DoDiff(sqls, nil); err != nil {
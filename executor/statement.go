@@ -0,0 +1,220 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// rowsKeywords are the leading keywords of statements which return a result set.
+var rowsKeywords = map[string]bool{
+	"select":   true,
+	"show":     true,
+	"desc":     true,
+	"describe": true,
+	"explain":  true,
+	"with":     true,
+	"values":   true,
+	"table":    true,
+	"help":     true,
+	"call":     true,
+	"execute":  true,
+	"analyze":  true,
+	"check":    true,
+	"checksum": true,
+	"optimize": true,
+	"repair":   true,
+	"trace":    true,
+	"admin":    true,
+	"(":        true,
+}
+
+// stripComments removes the leading spaces and comments of the statement.
+func stripComments(query string) string {
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(query, "/*"):
+			end := strings.Index(query[2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			query = query[end+4:]
+		case strings.HasPrefix(query, "#"), strings.HasPrefix(query, "-- "), query == "--":
+			end := strings.Index(query, "\n")
+			if end < 0 {
+				return ""
+			}
+			query = query[end+1:]
+		default:
+			return query
+		}
+	}
+}
+
+// keyword returns the leading keyword of the statement in lower case.
+func keyword(query string) string {
+	query = stripComments(query)
+	if strings.HasPrefix(query, "(") {
+		return "("
+	}
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '_'
+	})
+	if end < 0 {
+		end = len(query)
+	}
+	return strings.ToLower(query[:end])
+}
+
+// returnsRows reports whether the statement returns a result set.
+func returnsRows(query string) bool {
+	return rowsKeywords[keyword(query)]
+}
+
+// Order is how the order of rows is compared.
+type Order int
+
+const (
+	// OrderAuto ignores the order of rows for queries without a top-level ORDER BY.
+	OrderAuto Order = iota
+	// Ordered always compares the order of rows.
+	Ordered
+	// Unordered always ignores the order of rows.
+	Unordered
+)
+
+// ParseOrder parses the order mode, which is one of auto, ordered and unordered.
+func ParseOrder(s string) (Order, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return OrderAuto, nil
+	case "ordered":
+		return Ordered, nil
+	case "unordered":
+		return Unordered, nil
+	}
+	return OrderAuto, fmt.Errorf("invalid order mode %q, expect auto, ordered or unordered", s)
+}
+
+// queryKeywords are the leading keywords of queries whose order of rows is
+// undefined without ORDER BY.
+var queryKeywords = map[string]bool{
+	"select": true,
+	"with":   true,
+	"values": true,
+	"table":  true,
+	"(":      true,
+}
+
+const hintPrefix = "tidiff:"
+
+// Hints returns the tidiff hints in the leading comments of the statement, which
+// are in the form `/* tidiff:name[=value] [name[=value]...] */`.
+func Hints(query string) map[string]string {
+	hints := map[string]string{}
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if !strings.HasPrefix(query, "/*") {
+			return hints
+		}
+		end := strings.Index(query[2:], "*/")
+		if end < 0 {
+			return hints
+		}
+		comment := strings.TrimSpace(query[2 : end+2])
+		query = query[end+4:]
+		if !strings.HasPrefix(comment, hintPrefix) {
+			continue
+		}
+		for _, field := range strings.Fields(comment[len(hintPrefix):]) {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) == 2 {
+				hints[parts[0]] = parts[1]
+			} else {
+				hints[parts[0]] = ""
+			}
+		}
+	}
+}
+
+// words calls fn with every word (in lower case) out of parentheses, quoted
+// strings and comments in the query.
+func words(query string, fn func(word string)) {
+	depth := 0
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			// Skip quoted strings and identifiers, backslash escapes only apply to strings
+			i++
+			for i < len(query) && query[i] != ch {
+				if query[i] == '\\' && ch != '`' {
+					i++
+				}
+				i++
+			}
+			i++
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += end + 4
+		case ch == '#' || strings.HasPrefix(query[i:], "-- "):
+			end := strings.Index(query[i:], "\n")
+			if end < 0 {
+				return
+			}
+			i += end + 1
+		case ch == '(':
+			depth++
+			i++
+		case ch == ')':
+			depth--
+			i++
+		case ch == '_' || unicode.IsLetter(rune(ch)):
+			start := i
+			for i < len(query) && (query[i] == '_' || query[i] == '$' || unicode.IsLetter(rune(query[i])) || unicode.IsDigit(rune(query[i]))) {
+				i++
+			}
+			if depth == 0 {
+				fn(strings.ToLower(query[start:i]))
+			}
+		default:
+			i++
+		}
+	}
+}
+
+// hasOrderBy reports whether the query has a top-level ORDER BY clause.
+func hasOrderBy(query string) bool {
+	var found bool
+	var prev string
+	words(query, func(word string) {
+		if prev == "order" && word == "by" {
+			found = true
+		}
+		prev = word
+	})
+	return found
+}
+
+// unordered reports whether to ignore the order of rows for the query, the hints
+// `tidiff:ordered` and `tidiff:unordered` take precedence over the order mode.
+func (e *Executor) unordered(query string) bool {
+	hints := Hints(query)
+	if _, found := hints["unordered"]; found {
+		return true
+	}
+	if _, found := hints["ordered"]; found {
+		return false
+	}
+	switch e.Order {
+	case Ordered:
+		return false
+	case Unordered:
+		return true
+	}
+	return queryKeywords[keyword(query)] && !hasOrderBy(query)
+}
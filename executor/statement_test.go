@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyword(t *testing.T) {
+	cases := map[string]string{
+		"select 1":                      "select",
+		"  /* comment */ SHOW tables":   "show",
+		"-- comment\n# other\nupdate t": "update",
+		"(select 1) union (select 2)":   "(",
+		"/* unterminated":               "",
+	}
+	for query, expected := range cases {
+		if k := keyword(query); k != expected {
+			t.Errorf("keyword(%q) = %q, expected %q", query, k, expected)
+		}
+	}
+}
+
+func TestHasOrderBy(t *testing.T) {
+	cases := map[string]bool{
+		"select * from t order by a":                             true,
+		"select * from t ORDER\n BY a limit 1":                   true,
+		"select * from (select * from t order by a) x":           false,
+		"select row_number() over (order by a) from t":           false,
+		"select 'order by' from t":                               false,
+		"select a from t -- order by a":                          false,
+		"select a from t union select b from s order by 1":       true,
+		"select `order` from t where `order` = 'by'":             false,
+		"select a from t where b = 'it\\'s' order by a /* x */ ": true,
+	}
+	for query, expected := range cases {
+		if found := hasOrderBy(query); found != expected {
+			t.Errorf("hasOrderBy(%q) = %v, expected %v", query, found, expected)
+		}
+	}
+}
+
+func TestHints(t *testing.T) {
+	hints := Hints("/* other */ /* tidiff:unordered seed=42 */ select /* tidiff:ordered */ 1")
+	expected := map[string]string{"unordered": "", "seed": "42"}
+	if !reflect.DeepEqual(hints, expected) {
+		t.Fatalf("expected %v, got %v", expected, hints)
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	e := &Executor{}
+	if !e.unordered("select * from t") || e.unordered("select * from t order by a") || e.unordered("show tables") {
+		t.Fatal("unexpected order in auto mode")
+	}
+	if e.unordered("/* tidiff:ordered */ select * from t") {
+		t.Fatal("expected the hint to take precedence")
+	}
+	e.Order = Unordered
+	if !e.unordered("select * from t order by a") {
+		t.Fatal("expected unordered")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/fill"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var fillCommand = &cli.Command{
+	Name:      "fill",
+	Usage:     "Insert the same random rows generated from the definition of a table into all backends",
+	ArgsUsage: "table rows",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "batch",
+			Value: 1000,
+			Usage: "Number of rows inserted by every INSERT statement",
+		},
+		&cli.Float64Flag{
+			Name:  "null",
+			Value: 0.1,
+			Usage: "Probability of NULL values of nullable columns",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Print the results of all INSERT statements instead of the divergent ones only",
+		},
+	},
+	Action: fillTable,
+}
+
+// referenceRows executes the query in all backends and returns the rows of
+// the reference backend.
+func referenceRows(exec *executor.Executor, query string) ([][]sql.NullString, error) {
+	results, err := exec.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	result := results[exec.Reference()]
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s in %s failed: %v", query, result.Backend.Name, result.Error)
+	}
+	return result.Rows, nil
+}
+
+// loadTable reads the definition of the table from the reference backend,
+// the table must exist in all backends.
+func loadTable(exec *executor.Executor, name string) (*fill.Table, error) {
+	results, err := exec.Query(fill.ColumnsQuery(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			return nil, fmt.Errorf("read the definition of table %s in %s failed: %v", name, result.Backend.Name, result.Error)
+		}
+		if len(result.Rows) == 0 {
+			return nil, fmt.Errorf("table %s not found in the current database of %s", name, result.Backend.Name)
+		}
+	}
+	uniques, err := referenceRows(exec, fill.UniquesQuery(name))
+	if err != nil {
+		return nil, err
+	}
+	foreignKeys, err := referenceRows(exec, fill.ForeignKeysQuery(name))
+	if err != nil {
+		return nil, err
+	}
+	return fill.NewTable(name, results[exec.Reference()].Rows, uniques, foreignKeys)
+}
+
+// newFiller returns the generator of the table, which excludes the existing
+// values of unique keys and picks the values of foreign keys from the
+// referenced tables.
+func newFiller(exec *executor.Executor, table *fill.Table, null float64) (*fill.Generator, error) {
+	g, err := fill.NewGenerator(table, exec.Seed, null)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range g.Keys() {
+		rows, err := referenceRows(exec, table.KeyQuery(key))
+		if err != nil {
+			return nil, err
+		}
+		g.Exclude(i, rows)
+	}
+	for i, fk := range g.ForeignKeys() {
+		rows, err := referenceRows(exec, fk.Query())
+		if err != nil {
+			return nil, err
+		}
+		g.Reference(i, rows)
+	}
+	return g, nil
+}
+
+func fillTable(ctx *cli.Context) error {
+	args := ctx.Args()
+	if args.Len() != 2 {
+		return errors.New("the table and the number of rows are required")
+	}
+	count, err := strconv.Atoi(args.Get(1))
+	if err != nil || count <= 0 {
+		return fmt.Errorf("invalid number of rows %q", args.Get(1))
+	}
+	batch := ctx.Int("batch")
+	if batch <= 0 {
+		return fmt.Errorf("invalid batch size %d", batch)
+	}
+	exec, err := openExecutor(ctx)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+	table, err := loadTable(exec, args.First())
+	if err != nil {
+		return err
+	}
+	g, err := newFiller(exec, table, ctx.Float64("null"))
+	if err != nil {
+		return err
+	}
+	// The rows are seeded like templates
+	fmt.Fprintf(os.Stderr, "filling %s with %d rows from seed %d\n", table.Name, count, exec.Seed)
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	out := newOutput(ctx)
+	summary := &script.Summary{}
+	seed := exec.Seed
+	for inserted, statements := 0, 0; inserted < count; {
+		select {
+		case <-interrupted:
+			return out.finish(summary)
+		default:
+		}
+		var rows [][]string
+		for len(rows) < batch && inserted+len(rows) < count {
+			row, err := g.Row()
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		query := g.Insert(rows)
+		results, err := exec.Query(query)
+		if err != nil {
+			return err
+		}
+		statements++
+		// All rows are generated from the seed of the command
+		result := &script.Result{
+			Statement:  &script.Statement{Text: query, File: table.Name, Line: statements},
+			Comparison: exec.Compare(results),
+			Seed:       &seed,
+		}
+		summary.Add(result)
+		out.handleGenerated(result)
+		// The following batches are meaningless if the reference fails
+		if err := results[exec.Reference()].Error; err != nil {
+			if ferr := out.finish(summary); ferr != nil {
+				fmt.Fprintln(os.Stderr, ferr.Error())
+			}
+			return fmt.Errorf("insert into %s in %s failed: %v", table.Name, results[exec.Reference()].Backend.Name, err)
+		}
+		inserted += len(rows)
+		fmt.Fprintf(os.Stderr, "%d/%d rows inserted\n", inserted, count)
+	}
+	return out.finish(summary)
+}
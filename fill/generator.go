@@ -0,0 +1,392 @@
+package fill
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pingcap/tidiff/directive"
+)
+
+// attempts is the number of times to generate a row again if its unique keys
+// are duplicated, before giving up.
+const attempts = 100
+
+// maxLength limits the length of generated strings and blobs.
+const maxLength = 64
+
+// Generator generates random rows of a table, whose values are rendered by
+// the template functions so that they are the same with the same seed.
+type Generator struct {
+	table *Table
+	rand  *rand.Rand
+	// columns are the inserted columns, whose values are rendered by the
+	// templates or picked from the foreign keys.
+	columns   []*Column
+	templates []*template.Template
+	keys      []*key
+	refs      []*reference
+}
+
+// key is a unique key, whose values already generated or existing are seen.
+type key struct {
+	columns []int
+	seen    map[string]bool
+}
+
+// reference is a foreign key, whose values are picked from the rows of the
+// referenced table.
+type reference struct {
+	fk       *ForeignKey
+	columns  []int
+	nullable bool
+	values   [][]string
+}
+
+func index(columns []*Column, name string) int {
+	for i, c := range columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexes(columns []*Column, names []string) []int {
+	result := make([]int, len(names))
+	for i, name := range names {
+		if result[i] = index(columns, name); result[i] < 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+// NewGenerator returns the generator of the table, the nullable columns are
+// NULL with the probability of null.
+func NewGenerator(t *Table, seed int64, null float64) (*Generator, error) {
+	g := &Generator{table: t, rand: rand.New(rand.NewSource(seed))}
+	for _, c := range t.Columns {
+		if !c.Skipped {
+			g.columns = append(g.columns, c)
+		}
+	}
+	if len(g.columns) == 0 {
+		return nil, fmt.Errorf("no columns of table %s to insert", t.Name)
+	}
+	functions := directive.Functions(g.rand)
+	for _, c := range g.columns {
+		expr, err := Expression(c)
+		if err != nil {
+			return nil, err
+		}
+		if c.Nullable && null > 0 {
+			expr = fmt.Sprintf("nullable %s (%s)", strconv.FormatFloat(null, 'g', -1, 64), expr)
+		}
+		temp, err := template.New(c.Name).Funcs(functions).Parse("{{" + expr + "}}")
+		if err != nil {
+			return nil, err
+		}
+		g.templates = append(g.templates, temp)
+	}
+	// The unique keys of skipped columns are unique by the backends
+	for _, names := range t.Uniques {
+		if columns := indexes(g.columns, names); columns != nil {
+			g.keys = append(g.keys, &key{columns: columns, seen: map[string]bool{}})
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		columns := indexes(g.columns, fk.Columns)
+		if columns == nil {
+			continue
+		}
+		ref := &reference{fk: fk, columns: columns, nullable: true}
+		for _, i := range columns {
+			ref.nullable = ref.nullable && g.columns[i].Nullable
+			g.templates[i] = nil
+		}
+		g.refs = append(g.refs, ref)
+	}
+	return g, nil
+}
+
+// Keys returns the columns of the unique keys, whose existing values should
+// be excluded by Exclude.
+func (g *Generator) Keys() [][]string {
+	var result [][]string
+	for _, k := range g.keys {
+		var names []string
+		for _, i := range k.columns {
+			names = append(names, g.columns[i].Name)
+		}
+		result = append(result, names)
+	}
+	return result
+}
+
+// Exclude excludes the existing values of the i-th unique key, which are the
+// rows of Table.KeyQuery.
+func (g *Generator) Exclude(i int, rows [][]sql.NullString) {
+	k := g.keys[i]
+	for _, row := range rows {
+		values := make([]string, len(g.columns))
+		for j, col := range k.columns {
+			if j < len(row) {
+				values[col] = g.columns[col].literal(row[j])
+			}
+		}
+		if id, ok := k.id(g.columns, values); ok {
+			k.seen[id] = true
+		}
+	}
+}
+
+// ForeignKeys returns the foreign keys of the inserted columns, whose values
+// should be set by Reference.
+func (g *Generator) ForeignKeys() []*ForeignKey {
+	var result []*ForeignKey
+	for _, ref := range g.refs {
+		result = append(result, ref.fk)
+	}
+	return result
+}
+
+// Reference sets the values of the i-th foreign key, which are the rows of
+// ForeignKey.Query.
+func (g *Generator) Reference(i int, rows [][]sql.NullString) {
+	ref := g.refs[i]
+	ref.values = nil
+	for _, row := range rows {
+		values := make([]string, len(ref.columns))
+		for j, col := range ref.columns {
+			if j < len(row) {
+				values[j] = g.columns[col].literal(row[j])
+			}
+		}
+		ref.values = append(ref.values, values)
+	}
+}
+
+// id returns the identity of the key in the row, which is false if any
+// value is NULL since NULLs never duplicate.
+func (k *key) id(columns []*Column, values []string) (string, bool) {
+	parts := make([]string, len(k.columns))
+	for i, col := range k.columns {
+		if values[col] == "NULL" {
+			return "", false
+		}
+		parts[i] = columns[col].key(values[col])
+	}
+	return strings.Join(parts, "\x00"), true
+}
+
+// Row generates a row whose unique keys are different from the existing and
+// generated ones, the values are SQL literals.
+func (g *Generator) Row() ([]string, error) {
+	for attempt := 0; attempt < attempts; attempt++ {
+		values, err := g.values()
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(g.keys))
+		duplicated := false
+		for i, k := range g.keys {
+			id, ok := k.id(g.columns, values)
+			if ok && k.seen[id] {
+				duplicated = true
+				break
+			}
+			ids[i] = id
+		}
+		if duplicated {
+			continue
+		}
+		for i, k := range g.keys {
+			if ids[i] != "" {
+				k.seen[ids[i]] = true
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("no unique row generated in %d attempts, the unique keys of table %s may run out of values", attempts, g.table.Name)
+}
+
+func (g *Generator) values() ([]string, error) {
+	values := make([]string, len(g.columns))
+	var b bytes.Buffer
+	for i, temp := range g.templates {
+		if temp == nil {
+			continue
+		}
+		b.Reset()
+		if err := temp.Execute(&b, nil); err != nil {
+			return nil, err
+		}
+		values[i] = b.String()
+	}
+	for _, ref := range g.refs {
+		if len(ref.values) == 0 && !ref.nullable {
+			return nil, fmt.Errorf("no rows in table %s referenced by the foreign key %s, fill it first", ref.fk.Table, ref.fk.Name)
+		}
+		n := len(ref.values)
+		if ref.nullable {
+			n++
+		}
+		picked := g.rand.Intn(n)
+		for j, col := range ref.columns {
+			if picked == len(ref.values) {
+				values[col] = "NULL"
+			} else {
+				values[col] = ref.values[picked][j]
+			}
+		}
+	}
+	return values, nil
+}
+
+// Insert renders the rows as an INSERT statement.
+func (g *Generator) Insert(rows [][]string) string {
+	var b strings.Builder
+	names := make([]string, len(g.columns))
+	for i, c := range g.columns {
+		names[i] = c.Name
+	}
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", quote(g.table.Name), quoteAll(names))
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(" + strings.Join(row, ", ") + ")")
+	}
+	return b.String()
+}
+
+// bits are the sizes of integer types.
+var bits = map[string]uint{"tinyint": 8, "smallint": 16, "mediumint": 24, "int": 32, "integer": 32, "bigint": 64}
+
+// Expression returns the template pipeline generating the literals of the
+// column, e.g. `quote (varchar 21)` of varchar(20).
+func Expression(c *Column) (string, error) {
+	unsigned := strings.Contains(strings.ToLower(c.ColumnType), "unsigned")
+	length := c.Length
+	if length > maxLength {
+		length = maxLength
+	}
+	switch c.Type {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		// The values of bigint are limited since the range of int is half-open
+		n := bits[c.Type]
+		if n == 64 {
+			n = 61
+		}
+		if unsigned {
+			return fmt.Sprintf("int 0 %d", uint64(1)<<n), nil
+		}
+		return fmt.Sprintf("int %d %d", -(int64(1) << (n - 1)), int64(1)<<(n-1)), nil
+	case "bit":
+		if length > 62 {
+			length = 62
+		}
+		return fmt.Sprintf("int 0 %d", int64(1)<<uint(length)), nil
+	case "decimal", "numeric":
+		if !unsigned {
+			return fmt.Sprintf("decimal %d %d", c.Precision, c.Scale), nil
+		}
+		integral := pow10(c.Precision - c.Scale)
+		if c.Scale == 0 {
+			return fmt.Sprintf("int 0 %d", integral), nil
+		}
+		scale := c.Scale
+		if scale > 18 {
+			scale = 18
+		}
+		return fmt.Sprintf(`printf "%%d.%%0%dd" (int 0 %d) (int 0 %d)`, scale, integral, pow10(scale)), nil
+	case "float":
+		if unsigned {
+			return "float 0 1e6", nil
+		}
+		return "float -1e6 1e6", nil
+	case "double", "real":
+		if unsigned {
+			return "double 0 1e9", nil
+		}
+		return "double -1e9 1e9", nil
+	case "char":
+		return fmt.Sprintf("quote (char %d)", length), nil
+	case "varchar", "tinytext", "text", "mediumtext", "longtext":
+		return fmt.Sprintf("quote (varchar %d)", length+1), nil
+	case "binary":
+		return fmt.Sprintf(`printf "X'%%s'" (hex %d)`, length), nil
+	case "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return fmt.Sprintf(`printf "X'%%s'" (hex (int 0 %d))`, length+1), nil
+	case "enum", "set":
+		values := enumValues(c.ColumnType)
+		if len(values) == 0 {
+			return "", fmt.Errorf("no values of %s column %s", c.Type, c.Name)
+		}
+		args := make([]string, len(values))
+		for i, v := range values {
+			args[i] = strconv.Quote(v)
+		}
+		return fmt.Sprintf("quote (%s %s)", c.Type, strings.Join(args, " ")), nil
+	case "date":
+		return `quote (date "1970-01-01" "2037-12-31")`, nil
+	case "datetime":
+		return `quote (datetime "1970-01-01" "2037-12-31 23:59:59")`, nil
+	case "timestamp":
+		// 1970-01-01 is out of range in the time zones east of UTC
+		return `quote (timestamp "1970-01-02" "2037-12-31 23:59:59")`, nil
+	case "time":
+		return `quote (time "-838:59:59" "838:59:59")`, nil
+	case "year":
+		return "year 1901 2155", nil
+	case "json":
+		return "quote (json 2)", nil
+	}
+	return "", fmt.Errorf("unsupported type %s of column %s", c.ColumnType, c.Name)
+}
+
+// pow10 returns 10^n limited to the range of int64.
+func pow10(n int64) int64 {
+	if n > 18 {
+		n = 18
+	}
+	result := int64(1)
+	for i := int64(0); i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// enumValues parses the values of enum('a','b') or set('a','b').
+func enumValues(columnType string) []string {
+	start, end := strings.Index(columnType, "("), strings.LastIndex(columnType, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+	var values []string
+	var value []rune
+	quoted := false
+	runes := []rune(columnType[start+1 : end])
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' && quoted && i+1 < len(runes) && runes[i+1] == '\'':
+			value = append(value, r)
+			i++
+		case r == '\'':
+			quoted = !quoted
+			if !quoted {
+				values = append(values, string(value))
+				value = value[:0]
+			}
+		case quoted:
+			value = append(value, r)
+		}
+	}
+	return values
+}
@@ -0,0 +1,190 @@
+package fill
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// row converts the cells to a fetched row, the nil cells are NULL.
+func row(cells ...interface{}) []sql.NullString {
+	result := make([]sql.NullString, len(cells))
+	for i, cell := range cells {
+		if s, ok := cell.(string); ok {
+			result[i] = sql.NullString{String: s, Valid: true}
+		}
+	}
+	return result
+}
+
+func TestTable(t *testing.T) {
+	table, err := NewTable("c",
+		[][]sql.NullString{
+			row("id", "int", "int", "NO", nil, "10", "0", "auto_increment"),
+			row("pid", "int", "int", "YES", nil, "10", "0", ""),
+			row("amount", "decimal", "decimal(6,2) unsigned", "NO", nil, "6", "2", ""),
+			row("kind", "enum", "enum('a','b''c','D')", "YES", "3", nil, nil, ""),
+			row("code", "char", "char(2)", "NO", "2", nil, nil, ""),
+			row("total", "int", "int", "YES", nil, "10", "0", "STORED GENERATED"),
+			row("created", "datetime", "datetime", "YES", nil, nil, nil, "DEFAULT_GENERATED"),
+		},
+		[][]sql.NullString{row("PRIMARY", "id"), row("uc", "code"), row("up", "pid"), row("up", "kind")},
+		[][]sql.NullString{row("fk", "pid", "p", "id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Columns) != 7 || !table.Columns[0].Skipped || !table.Column("KIND").Nullable {
+		t.Fatalf("unexpected columns %+v", table.Columns)
+	}
+	// The columns with expression defaults are inserted unlike generated columns
+	if !table.Column("total").Skipped || table.Column("created").Skipped {
+		t.Fatalf("unexpected skipped columns %+v", table.Columns)
+	}
+	if len(table.Uniques) != 3 || strings.Join(table.Uniques[2], ",") != "pid,kind" {
+		t.Fatalf("unexpected unique keys %v", table.Uniques)
+	}
+	if fk := table.ForeignKeys[0]; fk.Table != "p" || fk.Query() != "SELECT DISTINCT `id` FROM `p`" {
+		t.Fatalf("unexpected foreign key %+v", fk)
+	}
+	if _, err := NewTable("x", nil, nil, nil); err == nil {
+		t.Fatal("expected tables without columns to fail")
+	}
+
+	expressions := map[string]string{
+		"amount": `printf "%d.%02d" (int 0 10000) (int 0 100)`,
+		"kind":   `quote (enum "a" "b'c" "D")`,
+		"code":   `quote (char 2)`,
+	}
+	for name, expected := range expressions {
+		if expr, err := Expression(table.Column(name)); err != nil || expr != expected {
+			t.Fatalf("unexpected expression %s of %s: %v", expr, name, err)
+		}
+	}
+	if _, err := Expression(&Column{Name: "g", Type: "geometry", ColumnType: "geometry"}); err == nil {
+		t.Fatal("expected unsupported types to fail")
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	table, err := NewTable("c",
+		[][]sql.NullString{
+			row("id", "int", "int", "NO", nil, "10", "0", "auto_increment"),
+			row("pid", "int", "int", "NO", nil, "10", "0", ""),
+			row("code", "char", "char(1)", "NO", "1", nil, nil, ""),
+			row("note", "varchar", "varchar(10)", "YES", "10", nil, nil, ""),
+		},
+		[][]sql.NullString{row("PRIMARY", "id"), row("uc", "code")},
+		[][]sql.NullString{row("fk", "pid", "p", "id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate := func(seed int64, n int) ([][]string, error) {
+		g, err := NewGenerator(table, seed, 0.5)
+		if err != nil {
+			return nil, err
+		}
+		if keys := g.Keys(); len(keys) != 1 || keys[0][0] != "code" {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+		g.Exclude(0, [][]sql.NullString{row("a"), row("B")})
+		g.Reference(0, [][]sql.NullString{row("1"), row("2"), row("3")})
+		var result [][]string
+		for i := 0; i < n; i++ {
+			row, err := g.Row()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, row)
+		}
+		if insert := g.Insert(result[:1]); !strings.HasPrefix(insert, "INSERT INTO `c` (`pid`, `code`, `note`) VALUES (") {
+			t.Fatalf("unexpected insert %s", insert)
+		}
+		return result, nil
+	}
+	a, err := generate(42, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generate(42, 25)
+	// The strings are unique case-insensitively
+	codes := map[string]bool{"'a'": true, "'b'": true}
+	nulls := 0
+	for i, row := range a {
+		if strings.Join(row, ",") != strings.Join(b[i], ",") {
+			t.Fatalf("expected the same seed to generate the same rows: %v %v", row, b[i])
+		}
+		if row[0] != "1" && row[0] != "2" && row[0] != "3" {
+			t.Fatalf("unexpected foreign key value %s", row[0])
+		}
+		code := strings.ToLower(row[1])
+		if codes[code] {
+			t.Fatalf("duplicated unique key %s", row[1])
+		}
+		codes[code] = true
+		if row[2] == "NULL" {
+			nulls++
+		}
+	}
+	if nulls == 0 || nulls == len(a) {
+		t.Fatalf("expected some of nullable values to be NULL, got %d", nulls)
+	}
+	// char(1) has only 36 values case-insensitively, 2 of which exist
+	if _, err := generate(42, 35); err == nil {
+		t.Fatal("expected running out of unique values to fail")
+	}
+}
+
+func TestKey(t *testing.T) {
+	cases := []struct {
+		column *Column
+		a, b   string
+		same   bool
+	}{
+		{&Column{Type: "decimal"}, "-0.00", "0.00", true},
+		{&Column{Type: "decimal"}, "1.50", "1.5", true},
+		{&Column{Type: "decimal"}, "1.5", "1.05", false},
+		{&Column{Type: "int"}, "007", "7", true},
+		{&Column{Type: "double"}, "-0", "0", true},
+		{&Column{Type: "double"}, "1e3", "1000.0", true},
+		{&Column{Type: "float"}, "123.4567891", "123.4568", true},
+		{&Column{Type: "varchar"}, "'Abc '", "'abc'", true},
+		{&Column{Type: "varchar"}, "'abc'", "'abd'", false},
+		{&Column{Type: "varbinary"}, "X'0A'", "X'0a'", true},
+		{&Column{Type: "bit"}, "5", "05", true},
+	}
+	for _, c := range cases {
+		if same := c.column.key(c.a) == c.column.key(c.b); same != c.same {
+			t.Errorf("%s keys of %s and %s: got same %v", c.column.Type, c.a, c.b, same)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	cases := []struct {
+		column   *Column
+		value    sql.NullString
+		expected string
+	}{
+		{&Column{Type: "int"}, sql.NullString{String: "-1", Valid: true}, "-1"},
+		{&Column{Type: "int"}, sql.NullString{}, "NULL"},
+		{&Column{Type: "varchar"}, sql.NullString{String: `it's \`, Valid: true}, `'it''s \\'`},
+		{&Column{Type: "bit"}, sql.NullString{String: "\x01\x02", Valid: true}, "258"},
+		{&Column{Type: "varbinary"}, sql.NullString{String: "\x00\xab", Valid: true}, "X'00ab'"},
+	}
+	for _, c := range cases {
+		if literal := c.column.literal(c.value); literal != c.expected {
+			t.Errorf("%s literal of %q: got %s, expected %s", c.column.Type, c.value.String, literal, c.expected)
+		}
+	}
+	// The generated timestamps are valid in all time zones
+	if expr, _ := Expression(&Column{Name: "t", Type: "timestamp"}); !strings.Contains(expr, `"1970-01-02"`) {
+		t.Fatalf("unexpected expression %s", expr)
+	}
+}
+
+func TestEnumValues(t *testing.T) {
+	values := enumValues("set('a,b','it''s','','(x)')")
+	if strings.Join(values, "|") != "a,b|it's||(x)" {
+		t.Fatalf("unexpected values %q", values)
+	}
+}
@@ -0,0 +1,240 @@
+// Package fill generates random rows from the definition of a table, so that
+// the same data can be inserted into all backends.
+package fill
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Column is a column of the table.
+type Column struct {
+	Name string
+	// Type is the data type, e.g. varchar, and ColumnType is the full type,
+	// e.g. varchar(20) or int unsigned.
+	Type       string
+	ColumnType string
+	Nullable   bool
+	// Length is the maximum length of strings or the number of bits.
+	Length    int64
+	Precision int64
+	Scale     int64
+	// Skipped columns are filled by the backends, e.g. auto increment and
+	// generated columns, which aren't inserted. The columns with expression
+	// defaults (DEFAULT_GENERATED) are inserted.
+	Skipped bool
+}
+
+// ForeignKey is a foreign key of the table, whose values are picked from the
+// rows of the referenced table.
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	Table      string
+	References []string
+}
+
+// Table is the definition of a table.
+type Table struct {
+	Name    string
+	Columns []*Column
+	// Uniques are the columns of the primary key and unique keys.
+	Uniques     [][]string
+	ForeignKeys []*ForeignKey
+}
+
+// literal quotes the string like the quote function of templates.
+func literal(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// quote quotes the identifier with backticks.
+func quote(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// ColumnsQuery lists the columns of the table in the current database.
+func ColumnsQuery(table string) string {
+	return "SELECT column_name, data_type, column_type, is_nullable, character_maximum_length, " +
+		"numeric_precision, numeric_scale, extra FROM information_schema.columns " +
+		"WHERE table_schema = DATABASE() AND table_name = " + literal(table) + " ORDER BY ordinal_position"
+}
+
+// UniquesQuery lists the columns of the unique keys of the table.
+func UniquesQuery(table string) string {
+	return "SELECT index_name, column_name FROM information_schema.statistics " +
+		"WHERE table_schema = DATABASE() AND table_name = " + literal(table) + " AND non_unique = 0 " +
+		"ORDER BY index_name, seq_in_index"
+}
+
+// ForeignKeysQuery lists the columns of the foreign keys of the table.
+func ForeignKeysQuery(table string) string {
+	return "SELECT constraint_name, column_name, referenced_table_name, referenced_column_name " +
+		"FROM information_schema.key_column_usage WHERE table_schema = DATABASE() AND table_name = " +
+		literal(table) + " AND referenced_table_name IS NOT NULL ORDER BY constraint_name, ordinal_position"
+}
+
+func parseInt(s sql.NullString) int64 {
+	n, _ := strconv.ParseInt(s.String, 10, 64)
+	return n
+}
+
+// NewTable builds the table from the rows of ColumnsQuery, UniquesQuery and
+// ForeignKeysQuery.
+func NewTable(name string, columns, uniques, foreignKeys [][]sql.NullString) (*Table, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found in the current database", name)
+	}
+	t := &Table{Name: name}
+	for _, row := range columns {
+		if len(row) < 8 {
+			return nil, fmt.Errorf("unexpected columns of table %s", name)
+		}
+		extra := strings.ToLower(row[7].String)
+		c := &Column{
+			Name:       row[0].String,
+			Type:       strings.ToLower(row[1].String),
+			ColumnType: row[2].String,
+			Nullable:   strings.EqualFold(row[3].String, "YES"),
+			Length:     parseInt(row[4]),
+			Precision:  parseInt(row[5]),
+			Scale:      parseInt(row[6]),
+			Skipped:    strings.Contains(extra, "auto_increment") || strings.Contains(extra, "virtual generated") || strings.Contains(extra, "stored generated"),
+		}
+		if c.Type == "bit" {
+			c.Length = c.Precision
+		}
+		t.Columns = append(t.Columns, c)
+	}
+	var index string
+	for _, row := range uniques {
+		if len(row) < 2 {
+			continue
+		}
+		if len(t.Uniques) == 0 || row[0].String != index {
+			index = row[0].String
+			t.Uniques = append(t.Uniques, nil)
+		}
+		t.Uniques[len(t.Uniques)-1] = append(t.Uniques[len(t.Uniques)-1], row[1].String)
+	}
+	var fk *ForeignKey
+	for _, row := range foreignKeys {
+		if len(row) < 4 {
+			continue
+		}
+		if fk == nil || fk.Name != row[0].String {
+			fk = &ForeignKey{Name: row[0].String, Table: row[2].String}
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+		}
+		fk.Columns = append(fk.Columns, row[1].String)
+		fk.References = append(fk.References, row[3].String)
+	}
+	return t, nil
+}
+
+// Column returns the column named name, or nil if not found.
+func (t *Table) Column(name string) *Column {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// KeyQuery selects the existing values of the columns, which are excluded
+// from the generated values of unique keys.
+func (t *Table) KeyQuery(columns []string) string {
+	return fmt.Sprintf("SELECT %s FROM %s", quoteAll(columns), quote(t.Name))
+}
+
+// Query selects the values of the referenced columns, which the values of the
+// foreign key are picked from.
+func (fk *ForeignKey) Query() string {
+	return fmt.Sprintf("SELECT DISTINCT %s FROM %s", quoteAll(fk.References), quote(fk.Table))
+}
+
+// numeric reports whether the values of the column are numeric literals,
+// otherwise they are quoted.
+func (c *Column) numeric() bool {
+	switch c.Type {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+		"decimal", "numeric", "float", "double", "real", "year":
+		return true
+	}
+	return false
+}
+
+// binary reports whether the values of the column are byte strings, which are
+// rendered as hexadecimal literals.
+func (c *Column) binary() bool {
+	switch c.Type {
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return true
+	}
+	return false
+}
+
+// literal renders a value of the column fetched from the backend as the same
+// literal as the generated ones.
+func (c *Column) literal(v sql.NullString) string {
+	switch {
+	case !v.Valid:
+		return "NULL"
+	case c.numeric():
+		return v.String
+	case c.Type == "bit":
+		// The values of bit are fetched as big-endian bytes
+		return new(big.Int).SetBytes([]byte(v.String)).String()
+	case c.binary():
+		return fmt.Sprintf("X'%x'", v.String)
+	}
+	return literal(v.String)
+}
+
+// key returns the literal as compared by unique keys, so that the values equal
+// in the backends have the same key, e.g. the numbers in any format and the
+// strings in case-insensitive collations. The collations aren't known, so the
+// strings are folded unless binary, which may only reject unique values.
+func (c *Column) key(literal string) string {
+	switch c.Type {
+	case "float", "double", "real":
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return literal
+		}
+		if f == 0 {
+			f = 0 // not -0
+		}
+		// The stored values are approximate, which are rounded conservatively
+		digits := 15
+		if c.Type == "float" {
+			digits = 6
+		}
+		return strconv.FormatFloat(f, 'g', digits, 64)
+	}
+	switch {
+	case c.numeric() || c.Type == "bit":
+		var r big.Rat
+		if _, ok := r.SetString(literal); ok {
+			return r.RatString()
+		}
+		return literal
+	case c.binary():
+		return strings.ToLower(literal)
+	}
+	// Trailing spaces are ignored by PAD SPACE collations
+	return strings.ToLower(strings.TrimRight(strings.TrimSuffix(literal, "'"), " "))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/fuzz"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var fuzzCommand = &cli.Command{
+	Name:  "fuzz",
+	Usage: "Execute random queries generated from the schema of the databases and save the divergent ones",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "count",
+			Usage: "Number of queries to execute, 0 to run until interrupted",
+		},
+		&cli.StringFlag{
+			Name:  "findings",
+			Value: "findings",
+			Usage: "Directory to save the divergent queries to",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Print the results of all queries instead of the divergent ones only",
+		},
+	},
+	Action: fuzzQueries,
+}
+
+// loadSchema introspects the schemas of the current databases in all backends,
+// and returns the tables and columns which exist in all of them.
+func loadSchema(exec *executor.Executor) (*fuzz.Schema, error) {
+	results, err := exec.Query(fuzz.SchemaQuery)
+	if err != nil {
+		return nil, err
+	}
+	var schema *fuzz.Schema
+	for _, result := range results {
+		if result.Error != nil {
+			return nil, fmt.Errorf("introspect the schema of %s failed: %v", result.Backend.Name, result.Error)
+		}
+		s := fuzz.NewSchema(result.Rows)
+		if schema == nil {
+			schema = s
+		} else {
+			schema = schema.Intersect(s)
+		}
+	}
+	if len(schema.Tables) == 0 {
+		return nil, errors.New("no tables found in the current databases of all backends")
+	}
+	return schema, nil
+}
+
+// saveFinding saves the divergent query with its seed and the verdicts.
+func saveFinding(dir string, seed int64, query string, c *executor.Comparison) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- seed: %d\n", seed)
+	for i, result := range c.Results {
+		if c.Differs[i] {
+			fmt.Fprintf(&b, "-- %s: %s\n", result.Backend.Name, c.Verdicts[i])
+		}
+	}
+	fmt.Fprintf(&b, "%s;\n", query)
+	path := filepath.Join(dir, fmt.Sprintf("%d.sql", seed))
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func fuzzQueries(ctx *cli.Context) error {
+	exec, err := openExecutor(ctx)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+	schema, err := loadSchema(exec)
+	if err != nil {
+		return err
+	}
+	dir := ctx.String("findings")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	// The queries are seeded like templates
+	seed := exec.Seed
+	fmt.Fprintf(os.Stderr, "fuzzing %d tables from seed %d\n", len(schema.Tables), seed)
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	out := newOutput(ctx)
+	summary := &script.Summary{}
+	count := ctx.Int("count")
+	for i := 0; count == 0 || i < count; i++ {
+		select {
+		case <-interrupted:
+			return out.finish(summary)
+		default:
+		}
+		// Every query has its own seed so that it can be generated again by `--seed n fuzz --count 1`
+		querySeed := seed + int64(i)
+		query := fuzz.NewGenerator(schema, querySeed).Query()
+		results, err := exec.Query(query)
+		if err != nil {
+			return err
+		}
+		result := &script.Result{
+			Statement:  &script.Statement{Text: query, File: "fuzz", Line: i + 1},
+			Comparison: exec.Compare(results),
+			Seed:       &querySeed,
+		}
+		summary.Add(result)
+		if result.Status() == script.Differed {
+			path, err := saveFinding(dir, querySeed, query, result.Comparison)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "divergent query of seed %d saved to %s\n", querySeed, path)
+		}
+		out.handleGenerated(result)
+		if (i+1)%1000 == 0 {
+			fmt.Fprintln(os.Stderr, summary.String())
+		}
+	}
+	return out.finish(summary)
+}
@@ -0,0 +1,356 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+
+	"github.com/pingcap/tidiff/directive"
+)
+
+// maxDepth limits the nesting of expressions, predicates and subqueries.
+const maxDepth = 3
+
+// function is a SQL function or operator, whose arguments are formatted into
+// the format in order.
+type function struct {
+	result Category
+	format string
+	args   []Category
+}
+
+var functions = []function{
+	{Numeric, "ABS(%s)", []Category{Numeric}},
+	{Numeric, "%s + %s", []Category{Numeric, Numeric}},
+	{Numeric, "%s - %s", []Category{Numeric, Numeric}},
+	{Numeric, "%s * %s", []Category{Numeric, Numeric}},
+	{Numeric, "ROUND(%s)", []Category{Numeric}},
+	{Numeric, "FLOOR(%s)", []Category{Numeric}},
+	{Numeric, "CEIL(%s)", []Category{Numeric}},
+	{Numeric, "MOD(%s, %s)", []Category{Numeric, small}},
+	{Numeric, "GREATEST(%s, %s)", []Category{Numeric, Numeric}},
+	{Numeric, "LEAST(%s, %s)", []Category{Numeric, Numeric}},
+	{Numeric, "COALESCE(%s, %s)", []Category{Numeric, Numeric}},
+	{Numeric, "IFNULL(%s, %s)", []Category{Numeric, Numeric}},
+	{Numeric, "IF(%s, %s, %s)", []Category{boolean, Numeric, Numeric}},
+	{Numeric, "CASE WHEN %s THEN %s ELSE %s END", []Category{boolean, Numeric, Numeric}},
+	{Numeric, "LENGTH(%s)", []Category{String}},
+	{Numeric, "CHAR_LENGTH(%s)", []Category{String}},
+	{Numeric, "YEAR(%s)", []Category{Temporal}},
+	{Numeric, "MONTH(%s)", []Category{Temporal}},
+	{String, "CONCAT(%s, %s)", []Category{String, String}},
+	{String, "UPPER(%s)", []Category{String}},
+	{String, "LOWER(%s)", []Category{String}},
+	{String, "SUBSTRING(%s, 1, %s)", []Category{String, small}},
+	{String, "LEFT(%s, %s)", []Category{String, small}},
+	{String, "TRIM(%s)", []Category{String}},
+	{String, "REPLACE(%s, 'a', 'b')", []Category{String}},
+	{String, "COALESCE(%s, %s)", []Category{String, String}},
+	{String, "IF(%s, %s, %s)", []Category{boolean, String, String}},
+	{String, "CAST(%s AS CHAR)", []Category{Numeric}},
+	{Temporal, "DATE(%s)", []Category{Temporal}},
+	{Temporal, "DATE_ADD(%s, INTERVAL %s DAY)", []Category{Temporal, small}},
+	{Temporal, "COALESCE(%s, %s)", []Category{Temporal, Temporal}},
+}
+
+var (
+	comparisons = []string{"=", "<>", "<", "<=", ">", ">=", "<=>"}
+	aggregates  = []string{"COUNT", "SUM", "MIN", "MAX", "AVG"}
+	joins       = []string{"JOIN", "LEFT JOIN", "RIGHT JOIN"}
+	words       = []string{"", "a", "b", "abc", "A", "z", " a", "a ", "0", "1", "10", "x%"}
+)
+
+// literals are the templates of the literals of every category, which are
+// rendered by the functions of the templates in scripts, so that the queries
+// are fuzzed with the typed values and the edge values of the types too.
+var literals = map[Category][]string{
+	Numeric: {`{{int -50 150}}`, `{{decimal 4 2}}`, `{{double -100 100}}`, `{{floatedge}}`,
+		`{{edge "int"}}`, `{{edge "bigint unsigned"}}`, `{{edge "decimal"}}`},
+	String: {`{{quote (varchar 6)}}`, `{{quote (unicode 2)}}`, `{{edge "char"}}`, `{{edge "json"}}`},
+	Temporal: {`{{quote (date "2000-01-01" "2030-12-31")}}`, `{{quote (datetime "2000-01-01" "2030-12-31")}}`,
+		`{{edge "date"}}`, `{{edge "datetime"}}`, `{{edge "timestamp"}}`},
+}
+
+// Generator generates random queries from the schema, the same seed generates
+// the same queries from the same schema.
+type Generator struct {
+	schema  *Schema
+	rand    *rand.Rand
+	aliases int
+	// literals are the templates of literals drawing from rand.
+	literals map[Category][]*template.Template
+}
+
+func NewGenerator(schema *Schema, seed int64) *Generator {
+	g := &Generator{schema: schema, rand: rand.New(rand.NewSource(seed)), literals: map[Category][]*template.Template{}}
+	funcs := directive.Functions(g.rand)
+	for category, texts := range literals {
+		for _, text := range texts {
+			g.literals[category] = append(g.literals[category], template.Must(template.New("literal").Funcs(funcs).Parse(text)))
+		}
+	}
+	return g
+}
+
+// ref is a column in the scope of a query.
+type ref struct {
+	expr     string
+	category Category
+}
+
+// scope is the columns which can be referenced by the expressions of a query.
+type scope []ref
+
+func (s scope) of(category Category) []ref {
+	var refs []ref
+	for _, r := range s {
+		if r.category == category {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// Query generates a random SELECT statement. The select items are aliased as
+// c1, c2 ... since the generated names of expressions may differ between
+// backends.
+func (g *Generator) Query() string {
+	g.aliases = 0
+	return g.query(nil, 0)
+}
+
+func (g *Generator) chance(p float64) bool {
+	return g.rand.Float64() < p
+}
+
+func (g *Generator) query(outer scope, depth int) string {
+	tables := 1
+	if depth == 0 {
+		tables = []int{1, 1, 1, 2, 2, 3}[g.rand.Intn(6)]
+	}
+	from, local := g.from(tables)
+	sc := append(append(scope(nil), local...), outer...)
+
+	var items, groups []string
+	aggregate := g.chance(0.3)
+	if aggregate {
+		for i := g.rand.Intn(3); i > 0; i-- {
+			c := local[g.rand.Intn(len(local))]
+			groups = append(groups, c.expr)
+			items = append(items, c.expr)
+		}
+		for i := 1 + g.rand.Intn(2); i > 0; i-- {
+			items = append(items, g.aggregate(sc, depth))
+		}
+	} else {
+		for i := 1 + g.rand.Intn(4); i > 0; i-- {
+			items = append(items, g.expr(sc, Category(g.rand.Intn(3)), depth))
+		}
+		if depth == 0 && g.chance(0.2) {
+			items = append(items, g.scalarSubquery(sc, depth))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if !aggregate && g.chance(0.15) {
+		b.WriteString("DISTINCT ")
+	}
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s AS c%d", item, i+1)
+	}
+	b.WriteString(" FROM " + from)
+	if g.chance(0.7) {
+		b.WriteString(" WHERE " + g.predicate(sc, depth))
+	}
+	if len(groups) > 0 {
+		b.WriteString(" GROUP BY " + strings.Join(groups, ", "))
+	}
+	if aggregate && g.chance(0.3) {
+		fmt.Fprintf(&b, " HAVING %s %s %s", g.aggregate(sc, depth), g.pick(comparisons), g.literal(Numeric))
+	}
+	// Only ordering by all items makes LIMIT deterministic
+	if depth == 0 && g.chance(0.3) {
+		var positions []string
+		for i := range items {
+			positions = append(positions, fmt.Sprint(i+1))
+		}
+		fmt.Fprintf(&b, " ORDER BY %s LIMIT %d", strings.Join(positions, ", "), 1+g.rand.Intn(10))
+	}
+	return b.String()
+}
+
+// from returns the FROM clause of n tables joined randomly, and the columns of them.
+func (g *Generator) from(n int) (string, scope) {
+	var b strings.Builder
+	var sc scope
+	for i := 0; i < n; i++ {
+		table := g.schema.Tables[g.rand.Intn(len(g.schema.Tables))]
+		g.aliases++
+		alias := fmt.Sprintf("t%d", g.aliases)
+		var columns scope
+		for _, c := range table.Columns {
+			columns = append(columns, ref{expr: alias + "." + quote(c.Name), category: c.Category})
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "%s AS %s", quote(table.Name), alias)
+		} else {
+			on := g.joinCondition(sc, columns)
+			fmt.Fprintf(&b, " %s %s AS %s ON %s", g.pick(joins), quote(table.Name), alias, on)
+		}
+		sc = append(sc, columns...)
+	}
+	return b.String(), sc
+}
+
+// joinCondition returns an equality of the columns of the joined tables in the
+// same category, or a random predicate if none.
+func (g *Generator) joinCondition(left, right scope) string {
+	for _, i := range g.rand.Perm(len(right)) {
+		r := right[i]
+		if candidates := left.of(r.category); len(candidates) > 0 {
+			return fmt.Sprintf("%s = %s", candidates[g.rand.Intn(len(candidates))].expr, r.expr)
+		}
+	}
+	return g.predicate(append(append(scope(nil), left...), right...), maxDepth-1)
+}
+
+// expr returns a random expression of the category.
+func (g *Generator) expr(sc scope, category Category, depth int) string {
+	switch category {
+	case boolean:
+		return g.predicate(sc, depth)
+	case small:
+		return fmt.Sprint(1 + g.rand.Intn(5))
+	}
+	columns := sc.of(category)
+	r := g.rand.Float64()
+	switch {
+	case depth < maxDepth && r < 0.35:
+		return g.function(sc, category, depth+1)
+	case len(columns) > 0 && r < 0.8:
+		return columns[g.rand.Intn(len(columns))].expr
+	}
+	return g.literal(category)
+}
+
+func (g *Generator) function(sc scope, category Category, depth int) string {
+	var candidates []function
+	for _, f := range functions {
+		if f.result == category {
+			candidates = append(candidates, f)
+		}
+	}
+	f := candidates[g.rand.Intn(len(candidates))]
+	args := make([]interface{}, len(f.args))
+	for i, arg := range f.args {
+		args[i] = g.expr(sc, arg, depth)
+	}
+	expr := fmt.Sprintf(f.format, args...)
+	if strings.HasPrefix(f.format, "%s") || strings.HasPrefix(f.format, "CASE") {
+		// Operators are parenthesized to keep the precedence
+		expr = "(" + expr + ")"
+	}
+	return expr
+}
+
+// literal returns a random literal of the category, which is NULL sometimes.
+func (g *Generator) literal(category Category) string {
+	if g.chance(0.05) {
+		return "NULL"
+	}
+	if category == String && g.chance(0.5) {
+		// The words are likely to match each other and the patterns of LIKE
+		return "'" + g.pick(words) + "'"
+	}
+	temps := g.literals[category]
+	var b strings.Builder
+	if err := temps[g.rand.Intn(len(temps))].Execute(&b, nil); err != nil {
+		// The arguments of the templates are constant and valid
+		panic(err)
+	}
+	return b.String()
+}
+
+// predicate returns a random boolean expression.
+func (g *Generator) predicate(sc scope, depth int) string {
+	category := Category(g.rand.Intn(3))
+	n := 6
+	if depth < maxDepth {
+		n = 10
+	}
+	switch g.rand.Intn(n) {
+	case 0, 1, 2:
+		return fmt.Sprintf("%s %s %s", g.expr(sc, category, depth), g.pick(comparisons), g.expr(sc, category, depth))
+	case 3:
+		not := ""
+		if g.chance(0.5) {
+			not = "NOT "
+		}
+		return fmt.Sprintf("%s IS %sNULL", g.expr(sc, category, depth), not)
+	case 4:
+		return fmt.Sprintf("%s BETWEEN %s AND %s", g.expr(sc, category, depth), g.literal(category), g.literal(category))
+	case 5:
+		if category == String && g.chance(0.5) {
+			return fmt.Sprintf("%s LIKE '%s%%'", g.expr(sc, category, depth), g.pick(words[1:5]))
+		}
+		return fmt.Sprintf("%s IN (%s, %s, %s)", g.expr(sc, category, depth), g.literal(category), g.literal(category), g.literal(category))
+	case 6:
+		return fmt.Sprintf("(%s AND %s)", g.predicate(sc, depth+1), g.predicate(sc, depth+1))
+	case 7:
+		return fmt.Sprintf("(%s OR %s)", g.predicate(sc, depth+1), g.predicate(sc, depth+1))
+	case 8:
+		return fmt.Sprintf("NOT (%s)", g.predicate(sc, depth+1))
+	}
+	// Subqueries, which are correlated if referencing the outer columns
+	if g.chance(0.5) {
+		return fmt.Sprintf("EXISTS (%s)", g.query(sc, depth+1))
+	}
+	from, local := g.from(1)
+	column := local[g.rand.Intn(len(local))]
+	inner := append(append(scope(nil), local...), sc...)
+	outer := g.expr(sc, column.category, depth+1)
+	return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)", outer, column.expr, from, g.predicate(inner, depth+1))
+}
+
+// aggregate returns a random aggregate function.
+func (g *Generator) aggregate(sc scope, depth int) string {
+	name := g.pick(aggregates)
+	switch name {
+	case "COUNT":
+		switch g.rand.Intn(3) {
+		case 0:
+			return "COUNT(*)"
+		case 1:
+			return fmt.Sprintf("COUNT(DISTINCT %s)", g.expr(sc, Category(g.rand.Intn(3)), maxDepth))
+		}
+		return fmt.Sprintf("COUNT(%s)", g.expr(sc, Category(g.rand.Intn(3)), depth+1))
+	case "MIN", "MAX":
+		return fmt.Sprintf("%s(%s)", name, g.expr(sc, Category(g.rand.Intn(3)), depth+1))
+	}
+	return fmt.Sprintf("%s(%s)", name, g.expr(sc, Numeric, depth+1))
+}
+
+// scalarSubquery returns a subquery which returns a single aggregated value.
+func (g *Generator) scalarSubquery(sc scope, depth int) string {
+	from, local := g.from(1)
+	inner := append(append(scope(nil), local...), sc...)
+	query := fmt.Sprintf("SELECT %s FROM %s", g.aggregate(local, depth+1), from)
+	if g.chance(0.7) {
+		query += " WHERE " + g.predicate(inner, depth+1)
+	}
+	return "(" + query + ")"
+}
+
+func (g *Generator) pick(choices []string) string {
+	return choices[g.rand.Intn(len(choices))]
+}
+
+// quote quotes the identifier with backticks.
+func quote(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
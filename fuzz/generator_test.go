@@ -0,0 +1,72 @@
+package fuzz
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func schemaRows(rows ...string) [][]sql.NullString {
+	var result [][]sql.NullString
+	for _, row := range rows {
+		var cells []sql.NullString
+		for _, cell := range strings.Split(row, ",") {
+			cells = append(cells, sql.NullString{String: cell, Valid: true})
+		}
+		result = append(result, cells)
+	}
+	return result
+}
+
+func TestSchema(t *testing.T) {
+	a := NewSchema(schemaRows("t,a,int,NO", "t,b,varchar,YES", "t,c,datetime,YES", "u,x,decimal,YES", "v,y,int,YES"))
+	b := NewSchema(schemaRows("t,A,int,NO", "t,c,timestamp,YES", "u,z,int,YES", "v,y,bigint,YES"))
+	if len(a.Tables) != 3 || len(a.Tables[0].Columns) != 3 {
+		t.Fatalf("unexpected schema %+v", a.Tables)
+	}
+	if c := a.Tables[0].Columns[2]; c.Category != Temporal || !c.Nullable || c.Type != "datetime" {
+		t.Fatalf("unexpected column %+v", c)
+	}
+	s := a.Intersect(b)
+	var names []string
+	for _, table := range s.Tables {
+		for _, c := range table.Columns {
+			names = append(names, table.Name+"."+c.Name)
+		}
+	}
+	if strings.Join(names, " ") != "t.a t.c v.y" {
+		t.Fatalf("unexpected intersection %v", names)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	schema := NewSchema(schemaRows("t,a,int,NO", "t,b,varchar,YES", "t,c,datetime,YES", "u,x,decimal,YES", "u,y,text,YES"))
+	tables := regexp.MustCompile("(?:FROM|JOIN) `(\\w+)` AS t\\d+")
+	var edges bool
+	for seed := int64(0); seed < 200; seed++ {
+		query := NewGenerator(schema, seed).Query()
+		edges = edges || strings.Contains(query, "'0000-00-00") || strings.Contains(query, "18446744073709551615")
+		if again := NewGenerator(schema, seed).Query(); again != query {
+			t.Fatalf("seed %d generates different queries:\n%s\n%s", seed, query, again)
+		}
+		if !strings.HasPrefix(query, "SELECT ") || !strings.Contains(query, " AS c1") {
+			t.Fatalf("unexpected query %s", query)
+		}
+		if strings.Count(query, "(") != strings.Count(query, ")") {
+			t.Fatalf("unbalanced parentheses %s", query)
+		}
+		for _, m := range tables.FindAllStringSubmatch(query, -1) {
+			if m[1] != "t" && m[1] != "u" {
+				t.Fatalf("unknown table %s in %s", m[1], query)
+			}
+		}
+	}
+	if !edges {
+		t.Fatal("expected the edge values of the types in the literals")
+	}
+	g := NewGenerator(schema, 1)
+	if g.Query() == g.Query() {
+		t.Fatal("expected different queries from the same generator")
+	}
+}
@@ -0,0 +1,120 @@
+// Package fuzz generates random queries from the schema of the databases to
+// find the differences between backends.
+package fuzz
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Category is the category of column types, the expressions of the same
+// category are comparable with each other.
+type Category int
+
+const (
+	Numeric Category = iota
+	String
+	Temporal
+	// boolean and small are the pseudo categories of function arguments, which
+	// are predicates and small positive integer literals.
+	boolean
+	small
+)
+
+// categorize returns the category of the data type in information_schema.
+func categorize(dataType string) Category {
+	switch strings.ToLower(dataType) {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+		"decimal", "numeric", "float", "double", "real", "bit":
+		return Numeric
+	case "date", "datetime", "timestamp", "time", "year":
+		return Temporal
+	}
+	return String
+}
+
+// Column is a column of a table.
+type Column struct {
+	Name     string
+	Type     string
+	Category Category
+	Nullable bool
+}
+
+// Table is a table or view of the database.
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+// Schema is the tables of the database which queries are generated from.
+type Schema struct {
+	Tables []*Table
+}
+
+// SchemaQuery lists the columns of the tables in the current database, whose
+// result set builds a schema by NewSchema.
+const SchemaQuery = "SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns " +
+	"WHERE table_schema = DATABASE() ORDER BY table_name, ordinal_position"
+
+// NewSchema builds the schema from the rows of SchemaQuery.
+func NewSchema(rows [][]sql.NullString) *Schema {
+	s := &Schema{}
+	var table *Table
+	for _, row := range rows {
+		if len(row) < 4 {
+			continue
+		}
+		if table == nil || table.Name != row[0].String {
+			table = &Table{Name: row[0].String}
+			s.Tables = append(s.Tables, table)
+		}
+		table.Columns = append(table.Columns, &Column{
+			Name:     row[1].String,
+			Type:     strings.ToLower(row[2].String),
+			Category: categorize(row[2].String),
+			Nullable: strings.EqualFold(row[3].String, "YES"),
+		})
+	}
+	return s
+}
+
+// Intersect returns the tables and columns which exist in both schemas, so
+// that the generated queries are valid in both. The types are of s.
+func (s *Schema) Intersect(o *Schema) *Schema {
+	result := &Schema{}
+	for _, t := range s.Tables {
+		ot := o.table(t.Name)
+		if ot == nil {
+			continue
+		}
+		table := &Table{Name: t.Name}
+		for _, c := range t.Columns {
+			if ot.column(c.Name) != nil {
+				table.Columns = append(table.Columns, c)
+			}
+		}
+		if len(table.Columns) > 0 {
+			result.Tables = append(result.Tables, table)
+		}
+	}
+	return result
+}
+
+func (s *Schema) table(name string) *Table {
+	for _, t := range s.Tables {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (t *Table) column(name string) *Column {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
@@ -1,22 +1,24 @@
 package main
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/pingcap/tidiff/config"
+	"github.com/pingcap/tidiff/diff"
 	"github.com/pingcap/tidiff/executor"
 	"github.com/pingcap/tidiff/history"
+	"github.com/pingcap/tidiff/report"
+	"github.com/pingcap/tidiff/script"
+	"github.com/pingcap/tidiff/suppress"
 	"github.com/pingcap/tidiff/uimode"
-	"github.com/sergi/go-diff/diffmatchpatch"
 	"gopkg.in/urfave/cli.v2"
 )
 
@@ -87,15 +89,105 @@ func main() {
 			Value: "charset=utf8mb4",
 			Usage: "TiDB DSN options",
 		},
+		&cli.StringSliceFlag{
+			Name:  "backend",
+			Usage: "Backend in the form name=[user[:password]@]host[:port][/db][?options], replaces the MySQL/TiDB pair if specified",
+		},
+		&cli.StringFlag{
+			Name:  "reference",
+			Value: "",
+			Usage: "Name of the backend which others will be compared with (default: the first backend)",
+		},
+		&cli.BoolFlag{
+			Name:  "warnings",
+			Value: true,
+			Usage: "Fetch SHOW WARNINGS after every statement and compare them",
+		},
+		&cli.BoolFlag{
+			Name:  "ignore-error-message",
+			Usage: "Compare errors by error code and SQLSTATE only",
+		},
+		&cli.StringFlag{
+			Name:  "order",
+			Value: "auto",
+			Usage: "Compare the order of rows: auto (ignored if no top-level ORDER BY), ordered or unordered",
+		},
+		&cli.Float64Flag{
+			Name:  "float.abs-epsilon",
+			Usage: "Absolute tolerance of FLOAT/DOUBLE values",
+		},
+		&cli.Float64Flag{
+			Name:  "float.rel-epsilon",
+			Usage: "Relative tolerance of FLOAT/DOUBLE values",
+		},
+		&cli.IntFlag{
+			Name:  "decimal.scale",
+			Value: -1,
+			Usage: "Round DECIMAL values to the number of fractional digits before comparing, -1 to keep as is",
+		},
+		&cli.BoolFlag{
+			Name:  "decimal.trim",
+			Usage: "Trim the trailing fractional zeros of DECIMAL values before comparing",
+		},
+		&cli.IntFlag{
+			Name:  "time.precision",
+			Value: -1,
+			Usage: "Truncate the fractional seconds of DATETIME/TIMESTAMP/TIME values to the number of digits, -1 to keep as is",
+		},
+		&cli.BoolFlag{
+			Name:  "string.ignore-case",
+			Usage: "Compare string values case-insensitively",
+		},
+		&cli.BoolFlag{
+			Name:  "metadata",
+			Usage: "Compare the column metadata (type, length, precision, scale and nullability) of result sets",
+		},
+		&cli.StringFlag{
+			Name:  "suppress",
+			Usage: "Suppress the known differences declared in the JSON file instead of reporting them",
+		},
+		&cli.Int64Flag{
+			Name:  "seed",
+			Usage: "Seed of the random values of the first template, the seed of every following template is one greater (default: the current time)",
+		},
+		&cli.StringFlag{
+			Name:  "output",
+			Value: "text",
+			Usage: "Output format of results: text or json (one JSON object per statement)",
+		},
+		&cli.StringFlag{
+			Name:  "junit",
+			Usage: "Write a JUnit XML report of the statements of scripts and test files to the file",
+		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "Write a self-contained HTML report of the statements of scripts and test files to the file",
+		},
 		&cli.StringFlag{
 			Name:  "log.diff",
 			Value: "",
 			Usage: "Log all query diff to file",
 		},
 	}
+	app.Before = func(ctx *cli.Context) error {
+		if err := initConfig(ctx); err != nil {
+			return err
+		}
+		return checkOutput(ctx)
+	}
 	app.Action = serve
+	app.Commands = []*cli.Command{
+		runCommand,
+		mysqltestCommand,
+		sqllogictestCommand,
+		recordCommand,
+		verifyCommand,
+		reduceCommand,
+		fuzzCommand,
+		fillCommand,
+	}
 	if err := app.Run(os.Args); err != nil {
-		println(err)
+		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 }
@@ -111,6 +203,67 @@ func dbConfig(dialect string, ctx *cli.Context) *executor.Config {
 	}
 }
 
+// backends returns the backends specified by `--backend` flags, or the MySQL/TiDB pair if absent.
+func backends(ctx *cli.Context) ([]*executor.Backend, error) {
+	specs := ctx.StringSlice("backend")
+	if len(specs) == 0 {
+		return []*executor.Backend{
+			executor.NewBackend("MySQL", dbConfig("mysql", ctx)),
+			executor.NewBackend("TiDB", dbConfig("tidb", ctx)),
+		}, nil
+	}
+	defaults := executor.Config{Host: "127.0.0.1", Port: 3306, User: "root", Options: "charset=utf8mb4"}
+	var result []*executor.Backend
+	for _, spec := range specs {
+		b, err := executor.ParseBackend(spec, defaults)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+func newExecutor(ctx *cli.Context) (*executor.Executor, error) {
+	bs, err := backends(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exec := executor.NewExecutor(bs...)
+	exec.FetchWarnings = ctx.Bool("warnings")
+	exec.IgnoreErrorMessage = ctx.Bool("ignore-error-message")
+	if exec.Order, err = executor.ParseOrder(ctx.String("order")); err != nil {
+		return nil, err
+	}
+	rules := &diff.Rules{
+		FloatAbsEpsilon: ctx.Float64("float.abs-epsilon"),
+		FloatRelEpsilon: ctx.Float64("float.rel-epsilon"),
+		DecimalScale:    ctx.Int("decimal.scale"),
+		TrimDecimal:     ctx.Bool("decimal.trim"),
+		TimePrecision:   ctx.Int("time.precision"),
+		IgnoreCase:      ctx.Bool("string.ignore-case"),
+	}
+	exec.CompareMetadata = ctx.Bool("metadata")
+	if *rules != *diff.NewRules() {
+		exec.Rules = rules
+	}
+	exec.Seed = ctx.Int64("seed")
+	if !ctx.IsSet("seed") {
+		exec.Seed = time.Now().UnixNano()
+	}
+	if path := ctx.String("suppress"); path != "" {
+		if exec.Suppressions, err = suppress.Load(path); err != nil {
+			return nil, err
+		}
+	}
+	if ref := ctx.String("reference"); ref != "" {
+		if err := exec.SetReference(ref); err != nil {
+			return nil, err
+		}
+	}
+	return exec, nil
+}
+
 func initConfig(ctx *cli.Context) error {
 	err := os.MkdirAll(filepath.Join(config.TiDiffPath), os.ModePerm)
 	if err != nil {
@@ -132,6 +285,12 @@ func initConfig(ctx *cli.Context) error {
 		return err
 	}
 
+	// Flags specified in command line take precedence over the configuration file. The set
+	// is collected in advance because slice flags (e.g. backend) may appear multiple times.
+	explicit := map[string]bool{}
+	for _, name := range ctx.FlagNames() {
+		explicit[name] = ctx.IsSet(name)
+	}
 	lines := strings.Split(string(b), "\n")
 	for _, line := range lines {
 		parts := strings.SplitN(line, "=", 2)
@@ -140,10 +299,7 @@ func initConfig(ctx *cli.Context) error {
 		}
 		parts[0] = strings.TrimSpace(parts[0])
 		parts[1] = strings.TrimSpace(parts[1])
-		if ctx.IsSet(parts[0]) {
-			continue
-		}
-		if ctx.IsSet(parts[0]) {
+		if explicit[parts[0]] {
 			continue
 		}
 		if err := ctx.Set(parts[0], parts[1]); err != nil {
@@ -155,63 +311,41 @@ func initConfig(ctx *cli.Context) error {
 
 func serveCLIMode(ctx *cli.Context, exec *executor.Executor) error {
 	query := strings.Join(ctx.Args().Slice(), " ")
-	mysqlResult, tidbResult, err := exec.Query(query)
+	results, err := exec.Query(query)
 	if err != nil {
 		return err
 	}
-	defer mysqlResult.Close()
-	defer tidbResult.Close()
-	mysqlContent, tidbContent := mysqlResult.Content(), tidbResult.Content()
-	var containsDiff bool
-	if mysqlResult.Error == nil && tidbResult.Error == nil {
+	c := exec.Compare(results)
+	if jsonOutput(ctx) {
+		result := &script.Result{Statement: &script.Statement{Text: query}, Comparison: c}
+		if err := printJSON(report.NewStatement(result)); err != nil {
+			return err
+		}
+	} else {
 		green := color.New(color.FgGreen).SprintFunc()
 		red := color.New(color.FgRed).SprintFunc()
-		patch := diffmatchpatch.New()
-		diff := patch.DiffMain(mysqlContent, tidbContent, false)
-		var newMySQLContent, newTiDBContent bytes.Buffer
-		for _, d := range diff {
-			switch d.Type {
-			case diffmatchpatch.DiffEqual:
-				newMySQLContent.WriteString(d.Text)
-				newTiDBContent.WriteString(d.Text)
-			case diffmatchpatch.DiffDelete:
-				newMySQLContent.WriteString(red(d.Text))
-				containsDiff = true
-			case diffmatchpatch.DiffInsert:
-				newTiDBContent.WriteString(green(d.Text))
-				containsDiff = true
-			}
-		}
-		mysqlContent = newMySQLContent.String()
-		tidbContent = newTiDBContent.String()
+		fmt.Print(c.Format(query, red, green))
 	}
-	logQuery := query
-	if strings.HasPrefix(query, "!!") {
-		logQuery = mysqlResult.Rendered
-	}
-	fmt.Println(fmt.Sprintf("MySQL(%s)> %s", exec.MySQLConfig.Address(), logQuery))
-	if mysqlContent != "" {
-		fmt.Println(mysqlContent)
-	}
-	fmt.Println(mysqlResult.Stat() + "\n")
-	fmt.Println(fmt.Sprintf("TiDB(%s)> %s", exec.TiDBConfig.Address(), logQuery))
-	if tidbContent != "" {
-		fmt.Println(tidbContent)
-	}
-	fmt.Println(tidbResult.Stat() + "\n")
-	if containsDiff {
-		return errors.New("inconsistant result between TiDB and MySQL")
+	if c.Diff {
+		var names []string
+		for _, result := range results {
+			names = append(names, result.Backend.Name)
+		}
+		return fmt.Errorf("inconsistant result between %s", strings.Join(names, ", "))
 	}
 	return nil
 }
 
 func serve(ctx *cli.Context) error {
-	if err := initConfig(ctx); err != nil {
+	exec, err := openExecutor(ctx)
+	if err != nil {
 		return err
 	}
-	exec := executor.NewExecutor(dbConfig("mysql", ctx), dbConfig("tidb", ctx))
-	if err := exec.Open(executor.DefaultRetryCnt); err != nil {
-		return err
+	defer exec.Close()
+
+	// Pipeline mode, statements are streamed from stdin
+	if args := ctx.Args(); (args.Len() == 1 && args.First() == "-") || (args.Len() == 0 && !isTerminal(os.Stdin)) {
+		return execScript(ctx, exec, "stdin", os.Stdin)
 	}
 
 	// Command line mode
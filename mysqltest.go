@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/tidiff/mysqltest"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var mysqltestCommand = &cli.Command{
+	Name:      "mysqltest",
+	Usage:     "Execute mysql-test files (.test) and report the differences",
+	ArgsUsage: "file.test|dir...",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "include-dir",
+			Usage: "Directories to search for the files of `source` commands besides the directory of the test file and its parent",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Print the results of all queries and the echo commands instead of the differed and errored queries only",
+		},
+	},
+	Action: runMySQLTest,
+}
+
+// testFiles returns the test files of the paths, the directories are walked
+// recursively for the files with the `.test` extension.
+func testFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && strings.HasSuffix(file, ".test") {
+				files = append(files, file)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func runMySQLTest(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("test files are required")
+	}
+	files, err := testFiles(ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	exec, err := openExecutor(ctx)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+
+	verbose := ctx.Bool("verbose")
+	cyan := color.New(color.FgCyan).SprintFunc()
+	out := newOutput(ctx)
+	runner := &mysqltest.Runner{
+		Executor: exec,
+		Handler:  out.handle,
+		Echo: func(text string) {
+			if verbose && !jsonOutput(ctx) {
+				fmt.Println(cyan(text))
+			}
+		},
+	}
+	total := &script.Summary{}
+	for _, file := range files {
+		cmds, err := mysqltest.Load(file, ctx.StringSlice("include-dir"))
+		if err != nil {
+			return err
+		}
+		summary, err := runner.Run(cmds)
+		total.Merge(summary)
+		var unsupported *mysqltest.UnsupportedError
+		if errors.As(err, &unsupported) {
+			fmt.Fprintln(os.Stderr, err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return out.finish(total)
+}
@@ -0,0 +1,140 @@
+package mysqltest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// errorNames are the symbols of the common server errors used by `--error`.
+var errorNames = map[string]uint16{
+	"ER_DB_CREATE_EXISTS":                1007,
+	"ER_DB_DROP_EXISTS":                  1008,
+	"ER_DBACCESS_DENIED_ERROR":           1044,
+	"ER_ACCESS_DENIED_ERROR":             1045,
+	"ER_NO_DB_ERROR":                     1046,
+	"ER_BAD_NULL_ERROR":                  1048,
+	"ER_BAD_DB_ERROR":                    1049,
+	"ER_TABLE_EXISTS_ERROR":              1050,
+	"ER_BAD_TABLE_ERROR":                 1051,
+	"ER_NON_UNIQ_ERROR":                  1052,
+	"ER_BAD_FIELD_ERROR":                 1054,
+	"ER_WRONG_FIELD_WITH_GROUP":          1055,
+	"ER_WRONG_GROUP_FIELD":               1056,
+	"ER_WRONG_VALUE_COUNT":               1058,
+	"ER_DUP_FIELDNAME":                   1060,
+	"ER_DUP_KEYNAME":                     1061,
+	"ER_DUP_ENTRY":                       1062,
+	"ER_WRONG_FIELD_SPEC":                1063,
+	"ER_PARSE_ERROR":                     1064,
+	"ER_EMPTY_QUERY":                     1065,
+	"ER_NONUNIQ_TABLE":                   1066,
+	"ER_INVALID_DEFAULT":                 1067,
+	"ER_MULTIPLE_PRI_KEY":                1068,
+	"ER_TOO_LONG_KEY":                    1071,
+	"ER_KEY_COLUMN_DOES_NOT_EXITS":       1072,
+	"ER_WRONG_AUTO_KEY":                  1075,
+	"ER_CANT_DROP_FIELD_OR_KEY":          1091,
+	"ER_UPDATE_TABLE_USED":               1093,
+	"ER_TOO_BIG_SELECT":                  1104,
+	"ER_UNKNOWN_ERROR":                   1105,
+	"ER_UNKNOWN_TABLE":                   1109,
+	"ER_FIELD_SPECIFIED_TWICE":           1110,
+	"ER_INVALID_GROUP_FUNC_USE":          1111,
+	"ER_TOO_BIG_ROWSIZE":                 1118,
+	"ER_WRONG_VALUE_COUNT_ON_ROW":        1136,
+	"ER_TABLEACCESS_DENIED_ERROR":        1142,
+	"ER_NO_SUCH_TABLE":                   1146,
+	"ER_NOT_ALLOWED_COMMAND":             1148,
+	"ER_SYNTAX_ERROR":                    1149,
+	"ER_DUP_UNIQUE":                      1169,
+	"ER_UNKNOWN_SYSTEM_VARIABLE":         1193,
+	"ER_WRONG_ARGUMENTS":                 1210,
+	"ER_WRONG_VALUE_FOR_VAR":             1231,
+	"ER_WRONG_TYPE_FOR_VAR":              1232,
+	"ER_NOT_SUPPORTED_YET":               1235,
+	"ER_OPERAND_COLUMNS":                 1241,
+	"ER_SUBQUERY_NO_1_ROW":               1242,
+	"ER_ILLEGAL_REFERENCE":               1247,
+	"ER_DERIVED_MUST_HAVE_ALIAS":         1248,
+	"ER_WARN_DATA_OUT_OF_RANGE":          1264,
+	"ER_WARN_DATA_TRUNCATED":             1265,
+	"ER_UNKNOWN_COLLATION":               1273,
+	"ER_TRUNCATED_WRONG_VALUE":           1292,
+	"ER_SP_DOES_NOT_EXIST":               1305,
+	"ER_WRONG_OBJECT":                    1347,
+	"ER_VIEW_WRONG_LIST":                 1353,
+	"ER_NO_DEFAULT_FOR_FIELD":            1364,
+	"ER_DIVISION_BY_ZERO":                1365,
+	"ER_TRUNCATED_WRONG_VALUE_FOR_FIELD": 1366,
+	"ER_DATA_TOO_LONG":                   1406,
+	"ER_TOO_BIG_SCALE":                   1425,
+	"ER_TOO_BIG_PRECISION":               1426,
+	"ER_M_BIGGER_THAN_D":                 1427,
+	"ER_ROW_IS_REFERENCED_2":             1451,
+	"ER_NO_REFERENCED_ROW_2":             1452,
+	"ER_DATA_OUT_OF_RANGE":               1690,
+	"ER_INVALID_JSON_TEXT":               3140,
+	"ER_INVALID_JSON_PATH":               3143,
+	"ER_INVALID_TYPE_FOR_JSON":           3146,
+	"ER_CHECK_CONSTRAINT_VIOLATED":       3819,
+}
+
+// expectedError is an error (or success) expected by `--error`.
+type expectedError struct {
+	// code is the error code, or 0 for success.
+	code     uint16
+	sqlState string
+	// any is set for the error names unknown to tidiff, which match any error.
+	any bool
+}
+
+// match reports whether err is the expected error.
+func (e expectedError) match(err error) bool {
+	if err == nil {
+		return e.code == 0 && e.sqlState == "" && !e.any
+	}
+	if e.any {
+		return true
+	}
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	if e.sqlState != "" {
+		return string(me.SQLState[:]) == e.sqlState
+	}
+	return me.Number == e.code
+}
+
+// parseErrors parses the argument of `--error`, which is a comma separated list
+// of error codes, error names (e.g. ER_DUP_ENTRY) or SQLSTATE values prefixed
+// with `S` (e.g. S42S02).
+func parseErrors(arg string) ([]expectedError, error) {
+	var expected []expectedError
+	for _, field := range strings.Split(arg, ",") {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "":
+			continue
+		case strings.HasPrefix(field, "ER_") || strings.HasPrefix(field, "WARN_"):
+			code, ok := errorNames[field]
+			expected = append(expected, expectedError{code: code, any: !ok})
+		case field[0] == 'S' && len(field) == 6:
+			expected = append(expected, expectedError{sqlState: field[1:]})
+		default:
+			code, err := strconv.ParseUint(field, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid error %s", field)
+			}
+			expected = append(expected, expectedError{code: uint16(code)})
+		}
+	}
+	if len(expected) == 0 {
+		return nil, errors.New("missing errors")
+	}
+	return expected, nil
+}
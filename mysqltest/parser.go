@@ -0,0 +1,259 @@
+// Package mysqltest runs the test files of the mysql-test framework (.test) in
+// all backends, the commands of mysqltest are honored as comparison hints.
+package mysqltest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// commands are the names of mysqltest commands, the statements starting with
+// other words are queries.
+var commands = map[string]bool{
+	"echo":                   true,
+	"error":                  true,
+	"sorted_result":          true,
+	"replace_column":         true,
+	"replace_result":         true,
+	"replace_regex":          true,
+	"delimiter":              true,
+	"query":                  true,
+	"query_vertical":         true,
+	"eval":                   true,
+	"let":                    true,
+	"source":                 true,
+	"connect":                true,
+	"connection":             true,
+	"disconnect":             true,
+	"sleep":                  true,
+	"real_sleep":             true,
+	"exit":                   true,
+	"skip":                   true,
+	"die":                    true,
+	"send":                   true,
+	"reap":                   true,
+	"vertical_results":       true,
+	"horizontal_results":     true,
+	"disable_warnings":       true,
+	"enable_warnings":        true,
+	"disable_query_log":      true,
+	"enable_query_log":       true,
+	"disable_result_log":     true,
+	"enable_result_log":      true,
+	"disable_abort_on_error": true,
+	"enable_abort_on_error":  true,
+	"disable_info":           true,
+	"enable_info":            true,
+	"disable_metadata":       true,
+	"enable_metadata":        true,
+	"require":                true,
+	"inc":                    true,
+	"dec":                    true,
+	"end":                    true,
+}
+
+// Command is a mysqltest command or a query in a test file.
+type Command struct {
+	// Name is the command name in lower case, which is empty for queries. The
+	// blocks are the commands `if` and `while` whose Text is the condition, and
+	// `end` for the closing brace.
+	Name string
+	// Text is the argument of the command or the text of the query.
+	Text string
+	File string
+	// Line is the line number where the command starts.
+	Line int
+}
+
+// Location returns the location of the command in the form `file:line`.
+func (c *Command) Location() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+// Parse splits a test file into commands and queries. The lines starting with
+// `--` are commands ended by the line, and the others are commands or queries
+// ended by the current delimiter which is changed by the `delimiter` command.
+// The lines starting with `#` are comments, and the blocks `if (condition)`
+// and `while (condition)` are enclosed in braces.
+func Parse(file string, r io.Reader) ([]*Command, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	delimiter := ";"
+	var cmds []*Command
+	var buf strings.Builder
+	var start int
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if buf.Len() == 0 {
+			trimmed := strings.TrimSpace(text)
+			switch {
+			case trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "{":
+				continue
+			case strings.HasPrefix(trimmed, "}"):
+				cmds = append(cmds, &Command{Name: "end", File: file, Line: line})
+				continue
+			case strings.HasPrefix(trimmed, "--"):
+				name, arg := split(trimmed[2:])
+				// Lines starting with `--` but not a command are comments
+				if commands[name] {
+					cmds = append(cmds, &Command{Name: name, Text: arg, File: file, Line: line})
+					if name == "delimiter" {
+						delimiter = arg
+					}
+				}
+				continue
+			}
+			if name, condition, ok := block(trimmed); ok {
+				cmds = append(cmds, &Command{Name: name, Text: condition, File: file, Line: line})
+				continue
+			}
+			start = line
+		}
+		buf.WriteString(text + "\n")
+
+		// A line may contain more than one statement
+		for {
+			stmt := buf.String()
+			end := indexDelimiter(stmt, delimiter)
+			if end < 0 {
+				break
+			}
+			buf.Reset()
+			if rest := stmt[end+len(delimiter):]; strings.TrimSpace(rest) != "" {
+				buf.WriteString(strings.TrimLeftFunc(rest, unicode.IsSpace))
+			}
+			stmt = strings.TrimSpace(stmt[:end])
+			if stmt == "" {
+				continue
+			}
+			cmd := &Command{Text: stmt, File: file, Line: start}
+			if name, arg := split(stmt); commands[name] {
+				cmd.Name, cmd.Text = name, arg
+				if name == "delimiter" {
+					delimiter = arg
+				}
+			}
+			cmds = append(cmds, cmd)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+		return nil, fmt.Errorf("%s:%d: statement isn't ended by the delimiter %s", file, start, delimiter)
+	}
+	return cmds, nil
+}
+
+// maxDepth limits the depth of nested `source` commands like mysqltest, which
+// stops sourcing the files recursively.
+const maxDepth = 16
+
+// Load parses the test file, and replaces the `source` commands with the
+// commands of the sourced files. The sourced files are searched in the
+// directory of the test file, its parent (i.e. the mysql-test directory of
+// the `t/` layout) and the include directories. Only the environment
+// variables are expanded in the paths.
+func Load(file string, includeDirs []string) ([]*Command, error) {
+	dir := filepath.Dir(file)
+	dirs := append([]string{dir, filepath.Dir(dir)}, includeDirs...)
+	return load(file, dirs, 0)
+}
+
+func load(file string, dirs []string, depth int) ([]*Command, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	cmds, err := Parse(file, f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	var result []*Command
+	for _, cmd := range cmds {
+		if cmd.Name != "source" {
+			result = append(result, cmd)
+			continue
+		}
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("%s: source nested more than %d levels", cmd.Location(), maxDepth)
+		}
+		path, err := resolve(cmd.Text, dirs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cmd.Location(), err)
+		}
+		sourced, err := load(path, dirs, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sourced...)
+	}
+	return result, nil
+}
+
+// resolve returns the path of the sourced file.
+func resolve(name string, dirs []string) (string, error) {
+	name = os.ExpandEnv(name)
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("source %s not found in %s", name, strings.Join(dirs, ", "))
+}
+
+// block parses the first line of a block, i.e. `if (condition)` or
+// `while (condition)` followed by an optional opening brace.
+func block(line string) (string, string, bool) {
+	name, rest := split(strings.Replace(line, "(", " (", 1))
+	if name != "if" && name != "while" || !strings.HasPrefix(rest, "(") {
+		return "", "", false
+	}
+	rest = strings.TrimSpace(strings.TrimSuffix(rest, "{"))
+	if !strings.HasSuffix(rest, ")") {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(rest[1 : len(rest)-1]), true
+}
+
+// split splits the statement into the leading word in lower case and the rest.
+func split(stmt string) (string, string) {
+	stmt = strings.TrimSpace(stmt)
+	end := strings.IndexFunc(stmt, unicode.IsSpace)
+	if end < 0 {
+		return strings.ToLower(stmt), ""
+	}
+	return strings.ToLower(stmt[:end]), strings.TrimSpace(stmt[end:])
+}
+
+// indexDelimiter returns the index of the first delimiter out of quoted strings
+// and identifiers, or -1 if absent.
+func indexDelimiter(text, delimiter string) int {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case strings.HasPrefix(text[i:], delimiter):
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,166 @@
+package mysqltest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := `# comment
+--source include/have_innodb.inc
+-- not a command
+create table t (a varchar(10));
+--error ER_DUP_ENTRY,1586
+insert into t values ('a;b'),
+  ("c\";d"); select 1;
+echo done;
+delimiter |;
+select 2|
+--delimiter ;
+--replace_column 1 # 2 'a b'
+select 3, 4;
+`
+	expected := []*Command{
+		{Name: "source", Text: "include/have_innodb.inc", Line: 2},
+		{Text: "create table t (a varchar(10))", Line: 4},
+		{Name: "error", Text: "ER_DUP_ENTRY,1586", Line: 5},
+		{Text: "insert into t values ('a;b'),\n  (\"c\\\";d\")", Line: 6},
+		{Text: "select 1", Line: 6},
+		{Name: "echo", Text: "done", Line: 8},
+		{Name: "delimiter", Text: "|", Line: 9},
+		{Text: "select 2", Line: 10},
+		{Name: "delimiter", Text: ";", Line: 11},
+		{Name: "replace_column", Text: "1 # 2 'a b'", Line: 12},
+		{Text: "select 3, 4", Line: 13},
+	}
+	for _, cmd := range expected {
+		cmd.File = "a.test"
+	}
+	cmds, err := Parse("a.test", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmds, expected) {
+		for i := range cmds {
+			t.Logf("%+v", *cmds[i])
+		}
+		t.Fatalf("unexpected commands")
+	}
+
+	if _, err := Parse("a.test", strings.NewReader("select 1")); err == nil {
+		t.Fatal("expect error for the statement without delimiter")
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	text := `if ($a)
+{
+  --echo a
+}
+while ($i < 3) {
+  select 1;
+}
+select if(1, 2, 3);
+`
+	expected := []*Command{
+		{Name: "if", Text: "$a", Line: 1},
+		{Name: "echo", Text: "a", Line: 3},
+		{Name: "end", Line: 4},
+		{Name: "while", Text: "$i < 3", Line: 5},
+		{Text: "select 1", Line: 6},
+		{Name: "end", Line: 7},
+		{Text: "select if(1, 2, 3)", Line: 8},
+	}
+	for _, cmd := range expected {
+		cmd.File = "a.test"
+	}
+	cmds, err := Parse("a.test", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmds, expected) {
+		for i := range cmds {
+			t.Logf("%+v", *cmds[i])
+		}
+		t.Fatalf("unexpected commands")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The files of the mysql-test layout are sourced relative to the parent of t/
+	write("t/a.test", "--source include/have.inc\nselect 2;\n")
+	write("include/have.inc", "select 1;\n--source include/more.inc\n")
+	write("include/more.inc", "select 3;\n")
+	cmds, err := Load(filepath.Join(dir, "t/a.test"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locations []string
+	for _, cmd := range cmds {
+		rel, _ := filepath.Rel(dir, cmd.File)
+		locations = append(locations, fmt.Sprintf("%s:%d %s", rel, cmd.Line, cmd.Text))
+	}
+	if strings.Join(locations, "|") != "include/have.inc:1 select 1|include/more.inc:1 select 3|t/a.test:2 select 2" {
+		t.Fatalf("unexpected commands %v", locations)
+	}
+
+	write("t/b.test", "--source other.inc\n")
+	if _, err := Load(filepath.Join(dir, "t/b.test"), nil); err == nil {
+		t.Fatal("expected the missing file to fail")
+	}
+	write("lib/other.inc", "select 4;\n")
+	if cmds, err := Load(filepath.Join(dir, "t/b.test"), []string{filepath.Join(dir, "lib")}); err != nil || len(cmds) != 1 {
+		t.Fatalf("expected the file in the include directory, got %v %v", cmds, err)
+	}
+	write("t/c.test", "--source t/c.test\n")
+	if _, err := Load(filepath.Join(dir, "t/c.test"), nil); err == nil {
+		t.Fatal("expected the recursive source to fail")
+	}
+}
+
+func TestParseArguments(t *testing.T) {
+	columns, err := parseColumns("1 # 3 'a b'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(columns, map[int]string{1: "#", 3: "a b"}) {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+	if _, err := parseColumns("1"); err == nil {
+		t.Fatal("expect error for the column without value")
+	}
+
+	regexps, err := parseRegexps(`/(a+)b/<\1>/ #X#y#i`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := "aab x"
+	for _, r := range regexps {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	if s != "<aa> y" {
+		t.Fatalf("unexpected replaced %q", s)
+	}
+
+	expected, err := parseErrors("ER_DUP_ENTRY, 1586, S42S02, ER_SOMETHING_NEW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, []expectedError{{code: 1062}, {code: 1586}, {sqlState: "42S02"}, {any: true}}) {
+		t.Fatalf("unexpected errors %v", expected)
+	}
+}
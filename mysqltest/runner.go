@@ -0,0 +1,501 @@
+package mysqltest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+// modifiers are the commands which only affect the next query.
+type modifiers struct {
+	// errors are expected by `--error`, whose argument is expectedText.
+	errors       []expectedError
+	expectedText string
+	// sorted is set by `--sorted_result` to ignore the order of rows.
+	sorted bool
+	// columns are the values to replace columns with by `--replace_column`,
+	// indexed by the column number starting from 1.
+	columns map[int]string
+	// results are the pairs of strings to replace by `--replace_result`.
+	results []string
+	// regexps are the replacements of `--replace_regex`.
+	regexps []replacement
+}
+
+type replacement struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// replace replaces the cells of the result set of every backend, so that the
+// values which are expected to vary (e.g. timestamps) don't make differences.
+func (m *modifiers) replace(result *executor.QueryResult) {
+	for _, row := range result.Rows {
+		for col := range row {
+			if value, ok := m.columns[col+1]; ok {
+				row[col] = sql.NullString{String: value, Valid: true}
+				continue
+			}
+			if !row[col].Valid {
+				continue
+			}
+			for i := 0; i+1 < len(m.results); i += 2 {
+				row[col].String = strings.Replace(row[col].String, m.results[i], m.results[i+1], -1)
+			}
+			for _, r := range m.regexps {
+				row[col].String = r.re.ReplaceAllString(row[col].String, r.repl)
+			}
+		}
+	}
+}
+
+// Runner executes the queries of test files in all backends one by one, and
+// hands the result of every query to the handler.
+type Runner struct {
+	Executor *executor.Executor
+	Handler  func(*script.Result)
+	// Echo is called with the text of `echo` commands.
+	Echo func(text string)
+}
+
+// ignored are the commands which only change the output of mysqltest, which
+// don't affect comparing.
+var ignored = map[string]bool{
+	"delimiter":              true,
+	"disable_query_log":      true,
+	"enable_query_log":       true,
+	"disable_info":           true,
+	"enable_info":            true,
+	"disable_metadata":       true,
+	"enable_metadata":        true,
+	"vertical_results":       true,
+	"horizontal_results":     true,
+	"disable_abort_on_error": true,
+	"enable_abort_on_error":  true,
+	"sleep":                  true,
+	"real_sleep":             true,
+	// The requirements are compared like the other queries
+	"require": true,
+}
+
+// UnsupportedError is returned for the test files using the commands which
+// need more than one session of every backend, e.g. `connect` and `send`,
+// which are skipped instead of compared wrongly.
+type UnsupportedError struct {
+	Command *Command
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s: unsupported command %s, the test file is skipped", e.Command.Location(), e.Command.Name)
+}
+
+// unsupported returns the first command which the runner can't emulate.
+func unsupported(cmds []*Command) *Command {
+	for _, cmd := range cmds {
+		switch cmd.Name {
+		case "connect", "disconnect", "send", "reap":
+			return cmd
+		case "connection":
+			if !strings.EqualFold(strings.TrimSpace(cmd.Text), "default") {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// state is the state of a test file changed by the commands, which lasts
+// until changed again unlike the modifiers.
+type state struct {
+	// warnings and results are disabled by `disable_warnings` and
+	// `disable_result_log`, which skip comparing the warnings and the rows.
+	warnings bool
+	results  bool
+	// vars are the variables set by `let`.
+	vars map[string]string
+}
+
+// hints returns the comparison hints of the next query.
+func (s *state) hints(m *modifiers) string {
+	var hints []string
+	if m.sorted {
+		hints = append(hints, "unordered")
+	}
+	if !s.results {
+		hints = append(hints, "ignore_rows")
+	}
+	if !s.warnings {
+		hints = append(hints, "ignore_warnings")
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+	return "/* tidiff:" + strings.Join(hints, " ") + " */ "
+}
+
+// variable is a variable reference, which is escaped by a backslash.
+var variable = regexp.MustCompile(`\\?\$(\w+)`)
+
+// expand replaces the variables in the text with the values set by `let`, or
+// the environment variables like mysqltest.
+func (s *state) expand(text string) (string, error) {
+	var err error
+	expanded := variable.ReplaceAllStringFunc(text, func(ref string) string {
+		if strings.HasPrefix(ref, "\\") {
+			return ref[1:]
+		}
+		name := ref[1:]
+		if value, ok := s.vars[name]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if err == nil {
+			err = fmt.Errorf("undefined variable $%s", name)
+		}
+		return ref
+	})
+	return expanded, err
+}
+
+// blocks matches the commands starting blocks with the ends of the blocks,
+// which returns the indexes of the ends by the starts and the other way round.
+func blocks(cmds []*Command) (map[int]int, map[int]int, error) {
+	ends, starts := map[int]int{}, map[int]int{}
+	var open []int
+	for i, cmd := range cmds {
+		switch cmd.Name {
+		case "if", "while":
+			open = append(open, i)
+		case "end":
+			if len(open) == 0 {
+				return nil, nil, fmt.Errorf("%s: unexpected end of block", cmd.Location())
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			ends[start], starts[i] = i, start
+		}
+	}
+	if len(open) > 0 {
+		return nil, nil, fmt.Errorf("%s: block isn't closed", cmds[open[len(open)-1]].Location())
+	}
+	return ends, starts, nil
+}
+
+// Run executes the commands of a test file. Failed or differed queries don't
+// stop the test, only the invalid commands do. The test files using the
+// commands which need more sessions fail with UnsupportedError before running.
+// The variables are expanded in `eval` queries and the arguments of commands
+// like mysqltest, but not in the other queries.
+func (r *Runner) Run(cmds []*Command) (*script.Summary, error) {
+	summary := &script.Summary{}
+	if cmd := unsupported(cmds); cmd != nil {
+		return summary, &UnsupportedError{Command: cmd}
+	}
+	ends, starts, err := blocks(cmds)
+	if err != nil {
+		return summary, err
+	}
+	m := &modifiers{}
+	s := &state{warnings: true, results: true, vars: map[string]string{}}
+	for i := 0; i < len(cmds); i++ {
+		cmd := cmds[i]
+		arg := cmd.Text
+		var err error
+		switch cmd.Name {
+		case "", "query", "query_vertical", "let", "inc", "dec", "if", "while":
+		default:
+			arg, err = s.expand(cmd.Text)
+		}
+		if err != nil {
+			return summary, fmt.Errorf("%s: %v", cmd.Location(), err)
+		}
+		switch cmd.Name {
+		case "", "query", "query_vertical", "eval":
+			result := r.exec(cmd, s.hints(m)+arg, m)
+			summary.Add(result)
+			if r.Handler != nil {
+				r.Handler(result)
+			}
+			m = &modifiers{}
+		case "echo":
+			if r.Echo != nil {
+				r.Echo(arg)
+			}
+		case "error":
+			m.errors, err = parseErrors(arg)
+			m.expectedText = arg
+		case "sorted_result":
+			m.sorted = true
+		case "replace_column":
+			m.columns, err = parseColumns(arg)
+		case "replace_result":
+			m.results = fields(arg)
+			if len(m.results)%2 != 0 {
+				err = errors.New("replace_result requires pairs of strings")
+			}
+		case "replace_regex":
+			m.regexps, err = parseRegexps(arg)
+		case "disable_warnings", "enable_warnings":
+			s.warnings = cmd.Name == "enable_warnings"
+		case "disable_result_log", "enable_result_log":
+			s.results = cmd.Name == "enable_result_log"
+		case "let":
+			err = r.let(s, cmd.Text)
+		case "inc", "dec":
+			err = s.add(cmd.Text, cmd.Name == "inc")
+		case "connection":
+			// Only the default connection is supported
+		case "die":
+			err = fmt.Errorf("die: %s", arg)
+		case "if", "while":
+			var ok bool
+			if ok, err = r.condition(s, arg); err == nil && !ok {
+				i = ends[i]
+			}
+		case "end":
+			// The condition of while is evaluated again
+			if start := starts[i]; cmds[start].Name == "while" {
+				i = start - 1
+			}
+		case "exit", "skip":
+			return summary, nil
+		default:
+			if !ignored[cmd.Name] {
+				err = fmt.Errorf("unsupported command %s", cmd.Name)
+			}
+		}
+		if err != nil {
+			return summary, fmt.Errorf("%s: %v", cmd.Location(), err)
+		}
+	}
+	return summary, nil
+}
+
+// let sets the variable by `let $name = value`.
+func (r *Runner) let(s *state, arg string) error {
+	parts := strings.SplitN(arg, "=", 2)
+	name := strings.TrimPrefix(strings.TrimSpace(parts[0]), "$")
+	if len(parts) != 2 || name == "" {
+		return fmt.Errorf("invalid let %s", arg)
+	}
+	value, err := r.value(s, parts[1])
+	if err != nil {
+		return err
+	}
+	s.vars[name] = value
+	return nil
+}
+
+// value evaluates the value of `let` and conditions, the value in backticks is
+// a query whose first cell in the reference backend is the value, and the
+// variables are expanded in the others.
+func (r *Runner) value(s *state, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "`") || !strings.HasSuffix(text, "`") || len(text) < 2 {
+		return s.expand(text)
+	}
+	query, err := s.expand(text[1 : len(text)-1])
+	if err != nil {
+		return "", err
+	}
+	results, err := r.Executor.Query(query)
+	if err != nil {
+		return "", err
+	}
+	ref := results[r.Executor.Reference()]
+	if ref.Error != nil {
+		return "", fmt.Errorf("query %s failed in %s: %v", query, ref.Backend.Name, ref.Error)
+	}
+	if len(ref.Rows) > 0 && len(ref.Rows[0]) > 0 && ref.Rows[0][0].Valid {
+		return ref.Rows[0][0].String, nil
+	}
+	return "", nil
+}
+
+// operators are the comparison operators of conditions, the longer ones first.
+var operators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// condition evaluates the condition of `if` and `while`, which is a value
+// optionally negated by `!`, or a comparison of two values. A value is true
+// if it's a non-zero number or a non-numeric non-empty string.
+func (r *Runner) condition(s *state, text string) (bool, error) {
+	text = strings.TrimSpace(text)
+	// The operators in queries aren't comparisons
+	if !strings.HasPrefix(strings.TrimPrefix(text, "!"), "`") {
+		for _, op := range operators {
+			if i := strings.Index(text, op); i > 0 {
+				a, err := r.value(s, text[:i])
+				if err != nil {
+					return false, err
+				}
+				b, err := r.value(s, text[i+len(op):])
+				if err != nil {
+					return false, err
+				}
+				return compare(a, b, op)
+			}
+		}
+	}
+	negated := strings.HasPrefix(text, "!")
+	value, err := r.value(s, strings.TrimPrefix(text, "!"))
+	if err != nil {
+		return false, err
+	}
+	truth := value != ""
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		truth = n != 0
+	}
+	return truth != negated, nil
+}
+
+// compare compares two values by the operator, which are compared as numbers
+// if both are numeric, or else only equality is defined.
+func compare(a, b, op string) (bool, error) {
+	x, errx := strconv.ParseFloat(a, 64)
+	y, erry := strconv.ParseFloat(b, 64)
+	if errx != nil || erry != nil {
+		switch op {
+		case "==":
+			return a == b, nil
+		case "!=":
+			return a != b, nil
+		}
+		return false, fmt.Errorf("%s %s %s requires numbers", a, op, b)
+	}
+	switch op {
+	case "==":
+		return x == y, nil
+	case "!=":
+		return x != y, nil
+	case "<=":
+		return x <= y, nil
+	case ">=":
+		return x >= y, nil
+	case "<":
+		return x < y, nil
+	}
+	return x > y, nil
+}
+
+// add increases or decreases the integer variable by `inc $name` or `dec $name`.
+func (s *state) add(arg string, inc bool) error {
+	name := strings.TrimPrefix(strings.TrimSpace(arg), "$")
+	n, err := strconv.Atoi(s.vars[name])
+	if err != nil {
+		return fmt.Errorf("variable $%s isn't an integer", name)
+	}
+	if inc {
+		n++
+	} else {
+		n--
+	}
+	s.vars[name] = strconv.Itoa(n)
+	return nil
+}
+
+// exec executes a query in all backends and compares the results with the modifiers.
+func (r *Runner) exec(cmd *Command, text string, m *modifiers) *script.Result {
+	stmt := &script.Statement{Text: text, File: cmd.File, Line: cmd.Line}
+	results, err := r.Executor.Query(text)
+	if err != nil {
+		return &script.Result{Statement: stmt, Err: err}
+	}
+	for _, result := range results {
+		m.replace(result)
+	}
+	c := r.Executor.Compare(results)
+	if len(m.errors) > 0 && !c.Failed() && !matchAny(m.errors, nil) {
+		// Succeeding consistently is no difference but fails the test
+		return &script.Result{Statement: stmt, Comparison: c, Err: fmt.Errorf("succeeded in all backends but expected to fail with %s", m.expectedText)}
+	}
+	expected := len(m.errors) > 0 && c.ExpectErrors(func(err error) bool {
+		return matchAny(m.errors, err)
+	})
+	return &script.Result{Statement: stmt, Comparison: c, Expected: expected}
+}
+
+func matchAny(expected []expectedError, err error) bool {
+	for _, e := range expected {
+		if e.match(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseColumns parses the argument of `--replace_column`, which is pairs of
+// column numbers starting from 1 and the values to replace with.
+func parseColumns(arg string) (map[int]string, error) {
+	args := fields(arg)
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, errors.New("replace_column requires pairs of column number and value")
+	}
+	columns := map[int]string{}
+	for i := 0; i < len(args); i += 2 {
+		col, err := strconv.Atoi(args[i])
+		if err != nil || col < 1 {
+			return nil, fmt.Errorf("invalid column number %s", args[i])
+		}
+		columns[col] = args[i+1]
+	}
+	return columns, nil
+}
+
+// backReference is the back reference in the replacement of `--replace_regex`.
+var backReference = regexp.MustCompile(`\\(\d)`)
+
+// parseRegexps parses the argument of `--replace_regex`, which is a list of
+// `/regex/replacement/` with an optional `i` flag for case insensitive matching.
+// Any character can be the separator instead of `/`.
+func parseRegexps(arg string) ([]replacement, error) {
+	var result []replacement
+	for arg = strings.TrimSpace(arg); arg != ""; arg = strings.TrimSpace(arg) {
+		sep := arg[:1]
+		parts := strings.SplitN(arg[1:], sep, 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid replace_regex %s", arg)
+		}
+		pattern, repl, rest := parts[0], parts[1], parts[2]
+		if strings.HasPrefix(rest, "i") {
+			pattern, rest = "(?i)"+pattern, rest[1:]
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		repl = backReference.ReplaceAllString(strings.Replace(repl, "$", "$$", -1), "$${$1}")
+		result = append(result, replacement{re: re, repl: repl})
+		arg = rest
+	}
+	return result, nil
+}
+
+// fields splits the argument by spaces, the quoted fields may contain spaces.
+func fields(arg string) []string {
+	var result []string
+	for arg = strings.TrimSpace(arg); arg != ""; arg = strings.TrimSpace(arg) {
+		if quote := arg[0]; quote == '\'' || quote == '"' || quote == '`' {
+			if end := strings.IndexByte(arg[1:], quote); end >= 0 {
+				result = append(result, arg[1:end+1])
+				arg = arg[end+2:]
+				continue
+			}
+		}
+		end := strings.IndexAny(arg, " \t")
+		if end < 0 {
+			end = len(arg)
+		}
+		result = append(result, arg[:end])
+		arg = arg[end:]
+	}
+	return result
+}
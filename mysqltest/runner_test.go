@@ -0,0 +1,133 @@
+package mysqltest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("TIDIFF_TEST_ENGINE", "InnoDB")
+	s := &state{vars: map[string]string{"a": "1", "ab": "2"}}
+	text, err := s.expand(`select $a, $ab, '\$a', $TIDIFF_TEST_ENGINE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `select 1, 2, '$a', InnoDB`; text != expected {
+		t.Fatalf("got %q, expected %q", text, expected)
+	}
+	if _, err := s.expand("select $undefined_variable"); err == nil {
+		t.Fatal("expected undefined variables to fail")
+	}
+}
+
+func TestHints(t *testing.T) {
+	s := &state{warnings: true, results: true}
+	if hints := s.hints(&modifiers{}); hints != "" {
+		t.Fatalf("unexpected hints %q", hints)
+	}
+	s.warnings, s.results = false, false
+	if hints := s.hints(&modifiers{sorted: true}); hints != "/* tidiff:unordered ignore_rows ignore_warnings */ " {
+		t.Fatalf("unexpected hints %q", hints)
+	}
+}
+
+func TestRunVariables(t *testing.T) {
+	cmds, err := Parse("t.test", strings.NewReader(`let $i = 1;
+--let $j = $i
+inc $i;
+--echo $i $j
+dec $j;
+--echo $j
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var echoed []string
+	r := &Runner{Echo: func(text string) { echoed = append(echoed, text) }}
+	if _, err := r.Run(cmds); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(echoed, "|") != "2 1|0" {
+		t.Fatalf("unexpected echoed text %q", echoed)
+	}
+}
+
+func TestRunBlocks(t *testing.T) {
+	cmds, err := Parse("t.test", strings.NewReader(`let $i = 0;
+while ($i < 3)
+{
+  if (!$i)
+  {
+    --echo zero
+  }
+  if ($i != 0) {
+    --echo $i
+  }
+  inc $i;
+}
+if ($i == 3) {
+  --echo three
+}
+if (abc) {
+  --echo string
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var echoed []string
+	r := &Runner{Echo: func(text string) { echoed = append(echoed, text) }}
+	if _, err := r.Run(cmds); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(echoed, "|") != "zero|1|2|three|string" {
+		t.Fatalf("unexpected echoed text %q", echoed)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"--echo $x\n", "t.test:1: undefined variable $x"},
+		{"let $s = a;\ninc $s;\n", "t.test:2: variable $s isn't an integer"},
+		{"--error x1\n", "t.test:1: invalid error x1"},
+		{"\n--die failed\n", "t.test:2: die: failed"},
+		{"if (1) {\n", "t.test:1: block isn't closed"},
+		{"}\n", "t.test:1: unexpected end of block"},
+		{"if (a < 1) {\n}\n", "t.test:1: a < 1 requires numbers"},
+	}
+	for _, c := range cases {
+		cmds, err := Parse("t.test", strings.NewReader(c.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = (&Runner{}).Run(cmds)
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%q: got error %v, expected %s", c.text, err, c.expected)
+		}
+	}
+}
+
+func TestRunUnsupported(t *testing.T) {
+	cmds, err := Parse("t.test", strings.NewReader(`connection default;
+--disable_query_log
+--require r/true.require
+select 1;
+connect (con1,localhost,root,,);
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No query is executed before the unsupported command is found
+	_, err = (&Runner{}).Run(cmds)
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Command.Line != 5 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err.Error() != "t.test:5: unsupported command connect, the test file is skipped" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pingcap/tidiff/report"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+// checkOutput validates the output format.
+func checkOutput(ctx *cli.Context) error {
+	switch ctx.String("output") {
+	case "text", "json":
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q, expect text or json", ctx.String("output"))
+}
+
+// jsonOutput reports whether the results are output as JSON.
+func jsonOutput(ctx *cli.Context) bool {
+	return ctx.String("output") == "json"
+}
+
+// printJSON prints the value as JSON in a line.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// output outputs the results of statements in the format of --output, and
+// collects them for the reports (--junit and --report) which are written at last.
+type output struct {
+	ctx   *cli.Context
+	print func(*script.Result)
+	junit *report.JUnit
+	html  *report.HTML
+}
+
+// newOutput returns the output of statements. The text format prints the
+// differed and errored statements only unless --verbose, while the JSON format
+// prints all statements one per line.
+func newOutput(ctx *cli.Context) *output {
+	o := &output{ctx: ctx, print: printResult(ctx.Bool("verbose"))}
+	if jsonOutput(ctx) {
+		o.print = func(result *script.Result) {
+			if err := printJSON(report.NewStatement(result)); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+		}
+	}
+	if ctx.String("junit") != "" {
+		o.junit = report.NewJUnit()
+	}
+	if ctx.String("report") != "" {
+		o.html = report.NewHTML()
+	}
+	return o
+}
+
+// handle outputs the result of a statement.
+func (o *output) handle(result *script.Result) {
+	o.print(result)
+	o.add(result)
+}
+
+// handleGenerated outputs the result of a generated statement, which is only
+// printed if it differed unless --verbose since most of them are the same, but
+// always added to the reports.
+func (o *output) handleGenerated(result *script.Result) {
+	if result.Status() == script.Differed || o.ctx.Bool("verbose") {
+		o.print(result)
+	}
+	o.add(result)
+}
+
+// add adds the result of a statement to the reports.
+func (o *output) add(result *script.Result) {
+	if o.junit != nil {
+		o.junit.Add(result)
+	}
+	if o.html != nil {
+		o.html.Add(result)
+	}
+}
+
+// finish outputs the summary of statements and writes the reports. It returns
+// an error if any statement differed so that tidiff exits with a non-zero code.
+func (o *output) finish(summary *script.Summary) error {
+	if jsonOutput(o.ctx) {
+		if err := printJSON(map[string]*script.Summary{"summary": summary}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(summary)
+	}
+	if o.junit != nil {
+		if err := writeReport(o.ctx.String("junit"), o.junit.Write); err != nil {
+			return err
+		}
+	}
+	if o.html != nil {
+		if err := writeReport(o.ctx.String("report"), o.html.Write); err != nil {
+			return err
+		}
+	}
+	if summary.Differed > 0 {
+		return fmt.Errorf("%d statements differed", summary.Differed)
+	}
+	return nil
+}
+
+// writeReport writes a report to the file by write.
+func writeReport(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/tidiff/baseline"
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var baselineFlag = &cli.StringFlag{
+	Name:  "baseline",
+	Usage: "Baseline file (default: the script file with the .baseline.json extension)",
+}
+
+var recordCommand = &cli.Command{
+	Name:      "record",
+	Usage:     "Execute a SQL script in the reference backend and record the results as the baseline",
+	ArgsUsage: "file.sql|-",
+	Flags:     []cli.Flag{baselineFlag},
+	Action:    recordBaseline,
+}
+
+var verifyCommand = &cli.Command{
+	Name:      "verify",
+	Usage:     "Execute a SQL script in the backends except the reference and compare the results with the baseline",
+	ArgsUsage: "file.sql|-",
+	Flags: []cli.Flag{
+		baselineFlag,
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Print the results of all statements instead of the differed and errored ones only",
+		},
+	},
+	Action: verifyBaseline,
+}
+
+// baselinePath returns the path of the baseline file of the script.
+func baselinePath(ctx *cli.Context, script string) (string, error) {
+	if path := ctx.String("baseline"); path != "" {
+		return path, nil
+	}
+	if ctx.Args().First() == "-" {
+		return "", errors.New("--baseline is required to read the script from stdin")
+	}
+	return strings.TrimSuffix(script, filepath.Ext(script)) + ".baseline.json", nil
+}
+
+// openBackends creates an executor of the backends chosen by fn from the
+// backends specified by the global flags, and opens it.
+func openBackends(ctx *cli.Context, fn func(exec *executor.Executor) []*executor.Backend) (*executor.Executor, error) {
+	exec, err := newExecutor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exec.Backends = fn(exec)
+	if len(exec.Backends) == 0 {
+		return nil, errors.New("no backends to execute the script")
+	}
+	if err := exec.SetReference(exec.Backends[0].Name); err != nil {
+		return nil, err
+	}
+	if err := exec.Open(executor.DefaultRetryCnt); err != nil {
+		return nil, err
+	}
+	return exec, nil
+}
+
+func recordBaseline(ctx *cli.Context) error {
+	path, r, err := openScript(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	output, err := baselinePath(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	exec, err := openBackends(ctx, func(exec *executor.Executor) []*executor.Backend {
+		return exec.Backends[exec.Reference() : exec.Reference()+1]
+	})
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+
+	ref := exec.Backends[0]
+	b := &baseline.Baseline{Backend: ref.Name, Address: ref.Config.Address()}
+	scanner := script.NewScanner(path, r)
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		results, err := exec.Query(stmt.Text)
+		if err != nil {
+			return fmt.Errorf("%s: %v", stmt.Location(), err)
+		}
+		b.Statements = append(b.Statements, baseline.NewEntry(stmt, results[0], exec.FetchWarnings))
+	}
+	if err := b.Save(output); err != nil {
+		return err
+	}
+	fmt.Printf("%d statements of %s recorded to %s\n", len(b.Statements), ref.Name, output)
+	return nil
+}
+
+func verifyBaseline(ctx *cli.Context) error {
+	path, r, err := openScript(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	input, err := baselinePath(ctx, path)
+	if err != nil {
+		return err
+	}
+	b, err := baseline.Load(input)
+	if err != nil {
+		return err
+	}
+	config, err := executor.ParseConfig(b.Address, executor.Config{})
+	if err != nil {
+		return err
+	}
+	ref := executor.NewBackend(b.Backend, config)
+
+	exec, err := openBackends(ctx, func(exec *executor.Executor) []*executor.Backend {
+		var others []*executor.Backend
+		for i, backend := range exec.Backends {
+			if i != exec.Reference() {
+				others = append(others, backend)
+			}
+		}
+		return others
+	})
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+
+	summary := &script.Summary{}
+	out := newOutput(ctx)
+	scanner := script.NewScanner(path, r)
+	for i := 0; ; i++ {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			if i < len(b.Statements) {
+				return fmt.Errorf("%s has more statements than %s, record the baseline again", input, path)
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if i >= len(b.Statements) || b.Statements[i].Query != stmt.Text {
+			return fmt.Errorf("%s: statement doesn't match the baseline %s, record the baseline again", stmt.Location(), input)
+		}
+		result := verifyStatement(exec, ref, stmt, b.Statements[i])
+		summary.Add(result)
+		out.handle(result)
+	}
+	return out.finish(summary)
+}
+
+// verifyStatement executes the statement as rendered in the baseline, so that
+// templates produce the same statements, and compares the results with the baseline.
+func verifyStatement(exec *executor.Executor, ref *executor.Backend, stmt *script.Statement, entry *baseline.Entry) *script.Result {
+	results, err := exec.Query(entry.Rendered)
+	if err != nil {
+		return &script.Result{Statement: stmt, Err: err}
+	}
+	recorded := entry.Result(ref)
+	if !exec.FetchWarnings || !entry.WarningsFetched {
+		// The warnings are only compared if fetched both when recording and now
+		recorded.Warnings, recorded.WarningsError = nil, nil
+		for _, result := range results {
+			result.Warnings, result.WarningsError = nil, nil
+		}
+	}
+	results = append([]*executor.QueryResult{recorded}, results...)
+	return &script.Result{Statement: stmt, Comparison: exec.CompareTo(results, 0)}
+}
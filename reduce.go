@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/reduce"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var reduceCommand = &cli.Command{
+	Name:      "reduce",
+	Usage:     "Minimize a SQL script to the statements which still reproduce its divergence",
+	ArgsUsage: "file.sql|-",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "database",
+			Usage: "Scratch database which is dropped and recreated in all backends before every run of the script (required)",
+		},
+		&cli.IntFlag{
+			Name:  "line",
+			Usage: "Line of the divergent statement to reproduce (default: the first divergent statement)",
+		},
+		&cli.StringFlag{
+			Name:  "out",
+			Usage: "File to write the reduced script to (default: the script file with the .reduced.sql extension)",
+		},
+	},
+	Action: reduceScript,
+}
+
+// reduction runs the candidate scripts of the reducer from a clean state.
+type reduction struct {
+	exec     *executor.Executor
+	database string
+}
+
+// reset recreates the scratch database in all backends with new sessions using it.
+func (r *reduction) reset() error {
+	if err := r.exec.Reconnect(); err != nil {
+		return err
+	}
+	name := "`" + strings.Replace(r.database, "`", "``", -1) + "`"
+	for _, query := range []string{"DROP DATABASE IF EXISTS " + name, "CREATE DATABASE " + name, "USE " + name} {
+		results, err := r.exec.Query(query)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Error != nil {
+				return fmt.Errorf("%s in %s failed: %v", query, result.Backend.Name, result.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// run executes the statements from a clean state and returns the comparison
+// of the last one.
+func (r *reduction) run(stmts []string) (*executor.Comparison, error) {
+	if err := r.reset(); err != nil {
+		return nil, err
+	}
+	var c *executor.Comparison
+	for _, stmt := range stmts {
+		results, err := r.exec.Query(stmt)
+		if err != nil {
+			return nil, err
+		}
+		c = r.exec.Compare(results)
+	}
+	return c, nil
+}
+
+// find executes the script from a clean state until the divergent statement,
+// which is the first one or the one at the line, and returns the executed
+// statements as rendered.
+func (r *reduction) find(scanner *script.Scanner, line int) ([]string, *script.Statement, string, error) {
+	if err := r.reset(); err != nil {
+		return nil, nil, "", err
+	}
+	var stmts []string
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			return nil, nil, "", errors.New("no divergent statement found in the script")
+		}
+		if err != nil {
+			return nil, nil, "", err
+		}
+		results, err := r.exec.Query(stmt.Text)
+		if err != nil {
+			// Never executed in any backend, e.g. an invalid template
+			continue
+		}
+		// Templates are reduced as rendered so that every run is the same
+		c := r.exec.Compare(results)
+		stmts = append(stmts, c.Results[c.Reference].Rendered)
+		if c.Diff && (line == 0 || stmt.Line == line) {
+			return stmts, stmt, reduce.Signature(c), nil
+		}
+	}
+}
+
+func reduceScript(ctx *cli.Context) error {
+	database := ctx.String("database")
+	if database == "" {
+		return errors.New("--database is required, which is dropped and recreated before every run")
+	}
+	path, r, err := openScript(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out := ctx.String("out")
+	if out == "" {
+		if path == "stdin" {
+			return errors.New("--out is required to read the script from stdin")
+		}
+		out = strings.TrimSuffix(path, filepath.Ext(path)) + ".reduced.sql"
+	}
+
+	exec, err := openExecutor(ctx)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+	red := &reduction{exec: exec, database: database}
+	stmts, stmt, signature, err := red.find(script.NewScanner(path, r), ctx.Int("line"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "reducing %d statements to reproduce %s (%s)\n", len(stmts), stmt.Location(), signature)
+
+	reducer := &reduce.Reducer{
+		Test: func(stmts []string) (bool, error) {
+			c, err := red.run(stmts)
+			if err != nil {
+				return false, err
+			}
+			return reduce.Signature(c) == signature, nil
+		},
+		// The reduced script is saved on progress so that it's kept if interrupted
+		Progress: func(stmts []string) {
+			fmt.Fprintf(os.Stderr, "reduced to %d statements, %d characters\n", len(stmts), len(strings.Join(stmts, "")))
+			if err := writeScript(out, stmts); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+		},
+	}
+	reduced, err := reducer.Reduce(stmts)
+	if err != nil {
+		return err
+	}
+	if err := writeScript(out, reduced); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "reduced %d statements to %d in %d runs, written to %s\n", len(stmts), len(reduced), reducer.Runs, out)
+	return nil
+}
+
+// writeScript writes the statements as a script, the statements containing
+// semicolons are written with another delimiter.
+func writeScript(path string, stmts []string) error {
+	var b strings.Builder
+	for _, stmt := range stmts {
+		if strings.Contains(stmt, ";") {
+			fmt.Fprintf(&b, "DELIMITER //\n%s//\nDELIMITER ;\n", stmt)
+			continue
+		}
+		fmt.Fprintf(&b, "%s;\n", stmt)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,197 @@
+// Package reduce minimizes scripts which reproduce divergences between backends
+// by delta debugging, so that the reproducers are small enough to report.
+package reduce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidiff/diff"
+	"github.com/pingcap/tidiff/executor"
+)
+
+// Reducer minimizes the statements which reproduce a divergence. The last
+// statement is the divergent one, and the others prepare the states for it.
+type Reducer struct {
+	// Test executes the statements from a clean state and reports whether they
+	// still reproduce the divergence.
+	Test func(stmts []string) (bool, error)
+	// Progress is called with the statements whenever they are reduced.
+	Progress func(stmts []string)
+	// Runs is the number of tests run.
+	Runs int
+}
+
+// Reduce returns the minimized statements. The preparing statements are
+// removed by delta debugging first, then each remaining statement is
+// simplified (e.g. clauses, predicates and list items are removed) as long as
+// the divergence reproduces.
+func (r *Reducer) Reduce(stmts []string) ([]string, error) {
+	if len(stmts) == 0 {
+		return nil, errors.New("no statements to reduce")
+	}
+	stmts = append([]string(nil), stmts...)
+	target := len(stmts) - 1
+	pick := func(kept []int) []string {
+		var result []string
+		for _, i := range kept {
+			result = append(result, stmts[i])
+		}
+		return append(result, stmts[target])
+	}
+	kept, err := r.ddmin(target, true, func(kept []int) (bool, error) {
+		return r.test(pick(kept))
+	})
+	if err != nil {
+		return nil, err
+	}
+	stmts = pick(kept)
+
+	for i := range stmts {
+		simplified, err := r.simplify(stmts[i], func(stmt string) (bool, error) {
+			candidate := append([]string(nil), stmts...)
+			candidate[i] = stmt
+			return r.test(candidate)
+		})
+		if err != nil {
+			return nil, err
+		}
+		stmts[i] = simplified
+	}
+	return stmts, nil
+}
+
+// test runs the test and reports the progress if the statements reproduce.
+func (r *Reducer) test(stmts []string) (bool, error) {
+	r.Runs++
+	ok, err := r.Test(stmts)
+	if err != nil {
+		return false, err
+	}
+	if ok && r.Progress != nil {
+		r.Progress(stmts)
+	}
+	return ok, nil
+}
+
+// ddmin returns the indexes of a 1-minimal subset of n items which passes the
+// test, which removes chunks of items while the rest passes, and halves the
+// chunks when none can be removed. The empty subset is tested only if allowed.
+func (r *Reducer) ddmin(n int, allowEmpty bool, test func(kept []int) (bool, error)) ([]int, error) {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	if n == 0 {
+		return items, nil
+	}
+	if allowEmpty {
+		ok, err := test(nil)
+		if err != nil || ok {
+			return nil, err
+		}
+	}
+	chunks := 2
+	for len(items) >= 2 {
+		if chunks > len(items) {
+			chunks = len(items)
+		}
+		reduced := false
+		for i := 0; i < chunks; i++ {
+			start, end := i*len(items)/chunks, (i+1)*len(items)/chunks
+			complement := append(append([]int(nil), items[:start]...), items[end:]...)
+			ok, err := test(complement)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				items, reduced = complement, true
+				if chunks > 2 {
+					chunks--
+				}
+				break
+			}
+		}
+		if !reduced {
+			if chunks == len(items) {
+				break
+			}
+			chunks *= 2
+		}
+	}
+	return items, nil
+}
+
+// simplify returns the simplest variant of the statement which passes the
+// test. The clauses are removed one by one first, then the items of lists are
+// reduced by delta debugging, until no more simplification passes.
+func (r *Reducer) simplify(stmt string, test func(string) (bool, error)) (string, error) {
+outer:
+	for {
+		for _, candidate := range removeClauses(stmt) {
+			ok, err := test(candidate)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				stmt = candidate
+				continue outer
+			}
+		}
+		for _, l := range lists(stmt) {
+			items, err := r.ddmin(len(l.items), false, func(kept []int) (bool, error) {
+				return test(l.keep(kept))
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(items) < len(l.items) {
+				stmt = l.keep(items)
+				continue outer
+			}
+		}
+		return stmt, nil
+	}
+}
+
+// Signature describes how the backends diverge from the reference in the
+// comparison, which is empty if they don't. The signature ignores the values,
+// e.g. `TiDB: rows` for different result sets and `TiDB: success vs error 1105`
+// for different errors, so that it stays the same while the script is reduced.
+func Signature(c *executor.Comparison) string {
+	ref := c.Results[c.Reference]
+	var parts []string
+	for i, result := range c.Results {
+		if !c.Differs[i] {
+			continue
+		}
+		if ref.Error != nil || result.Error != nil {
+			parts = append(parts, fmt.Sprintf("%s: %s vs %s", result.Backend.Name, executor.DescribeError(ref.Error), executor.DescribeError(result.Error)))
+			continue
+		}
+		var kinds []string
+		if d := c.Diffs[i]; d != nil {
+			if len(d.Columns) > 0 {
+				kinds = append(kinds, "columns")
+			}
+			if len(d.Metadata) > 0 {
+				kinds = append(kinds, "metadata")
+			}
+			for _, row := range d.Rows {
+				if row.Kind != diff.Equal {
+					kinds = append(kinds, "rows")
+					break
+				}
+			}
+		}
+		if d := c.WarningDiffs[i]; d != nil && !d.Equal() {
+			kinds = append(kinds, "warnings")
+		}
+		if result.Summary() != ref.Summary() {
+			kinds = append(kinds, "statistics")
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", result.Backend.Name, strings.Join(kinds, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
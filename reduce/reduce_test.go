@@ -0,0 +1,117 @@
+package reduce
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidiff/executor"
+)
+
+func TestTokenize(t *testing.T) {
+	stmt := "select `a b`, 'it\\'s' /* c */ from t1 -- x\nwhere a>=1.5"
+	tokens := tokenize(stmt)
+	expected := []string{"select", " ", "`a b`", ",", " ", "'it\\'s'", " ", "/* c */", " ", "from", " ", "t1", " ", "-- x", "\n", "where", " ", "a", ">", "=", "1.5"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatalf("unexpected tokens %q", tokens)
+	}
+	if strings.Join(tokens, "") != stmt {
+		t.Fatal("expected the tokens to concatenate to the statement")
+	}
+}
+
+func TestRemoveClauses(t *testing.T) {
+	variants := removeClauses("select a from t where a > (select max(b) from s where b < 3) order by a limit 1 union select 1")
+	expected := []string{
+		"select a from t order by a limit 1 union select 1",
+		"select a from t where a > (select max(b) from s where b < 3) limit 1 union select 1",
+		"select a from t where a > (select max(b) from s where b < 3) order by a union select 1",
+		"select a from t where a > (select max(b) from s where b < 3) order by a limit 1",
+		"select a from t where a > (select max(b) from s ) order by a limit 1 union select 1",
+	}
+	if !reflect.DeepEqual(variants, expected) {
+		t.Fatalf("unexpected variants %q", variants)
+	}
+}
+
+func TestLists(t *testing.T) {
+	var keeps []string
+	for _, l := range lists("select a, concat(b, 'x,y'), c from t where a = 1 and (b = 2 or c = 3)") {
+		keeps = append(keeps, l.keep([]int{len(l.items) - 1}))
+	}
+	expected := []string{
+		"select c from t where a = 1 and (b = 2 or c = 3)",
+		"select a, concat(b, 'x,y'), c from t where (b = 2 or c = 3)",
+		"select a, concat('x,y'), c from t where a = 1 and (b = 2 or c = 3)",
+		"select a, concat(b, 'x,y'), c from t where a = 1 and (c = 3)",
+	}
+	if !reflect.DeepEqual(keeps, expected) {
+		t.Fatalf("unexpected lists %q", keeps)
+	}
+	l := lists("insert into t values (1), (2), (3), (4)")[0]
+	if stmt := l.keep([]int{1, 3}); stmt != "insert into t values (2), (4)" {
+		t.Fatalf("unexpected statement %q", stmt)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	// The divergence reproduces if the table is created and the row 3 is
+	// inserted before selecting with a predicate on b.
+	r := &Reducer{Test: func(stmts []string) (bool, error) {
+		joined := strings.Join(stmts, ";")
+		last := stmts[len(stmts)-1]
+		return strings.Contains(joined, "create table t") && strings.Contains(joined, "(3)") &&
+			strings.HasPrefix(last, "select") && strings.Contains(last, "b > 1"), nil
+	}}
+	stmts := []string{
+		"create table t (a int)",
+		"create table s (b int)",
+		"insert into t values (1), (2), (3), (4)",
+		"insert into s values (1)",
+		"select a, b from t, s where a > 0 and b > 1 order by a",
+	}
+	reduced, err := r.Reduce(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"create table t (a int)",
+		"insert into t values (3)",
+		"select b from s where b > 1",
+	}
+	if !reflect.DeepEqual(reduced, expected) {
+		t.Fatalf("unexpected statements %q after %d runs", reduced, r.Runs)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	a := executor.NewBackend("MySQL", &executor.Config{Host: "127.0.0.1", Port: 3306})
+	b := executor.NewBackend("TiDB", &executor.Config{Host: "127.0.0.1", Port: 4000})
+	exec := executor.NewExecutor(a, b)
+	rows := func(values ...string) [][]sql.NullString {
+		var rows [][]sql.NullString
+		for _, v := range values {
+			rows = append(rows, []sql.NullString{{String: v, Valid: true}})
+		}
+		return rows
+	}
+	signature := func(x, y *executor.QueryResult) string {
+		x.Backend, y.Backend = a, b
+		return Signature(exec.Compare([]*executor.QueryResult{x, y}))
+	}
+	cases := []struct {
+		x, y      *executor.QueryResult
+		signature string
+	}{
+		{&executor.QueryResult{Columns: []string{"a"}, Rows: rows("1")}, &executor.QueryResult{Columns: []string{"a"}, Rows: rows("1")}, ""},
+		{&executor.QueryResult{Columns: []string{"a"}, Rows: rows("1")}, &executor.QueryResult{Columns: []string{"a"}, Rows: rows("2", "3")}, "TiDB: rows, statistics"},
+		{&executor.QueryResult{Columns: []string{"a"}, Rows: rows("1")}, &executor.QueryResult{Error: &mysql.MySQLError{Number: 1105}}, "TiDB: success vs error 1105"},
+	}
+	for _, c := range cases {
+		if s := signature(c.x, c.y); s != c.signature {
+			t.Errorf("expected signature %q, got %q", c.signature, s)
+		}
+	}
+}
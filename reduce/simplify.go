@@ -0,0 +1,263 @@
+package reduce
+
+import (
+	"strings"
+)
+
+// clauseKeywords start the clauses of statements, which split the lists of a
+// statement, e.g. the select list from the tables. The continuations are the
+// words which belong to the keywords if they follow, e.g. `ORDER BY`.
+var (
+	clauseKeywords = map[string]bool{
+		"select": true, "from": true, "where": true, "group": true, "having": true,
+		"order": true, "limit": true, "values": true, "value": true, "set": true,
+		"on": true, "using": true, "union": true, "except": true, "intersect": true,
+		"join": true, "into": true, "update": true, "delete": true, "insert": true,
+		"replace": true, "window": true, "partition": true, "when": true, "then": true,
+		"else": true, "duplicate": true,
+	}
+	continuations = map[string]bool{"by": true, "distinct": true, "all": true, "key": true}
+	// removable are the clauses which can be removed as a whole, the set
+	// operations are removed with the rest of the statement.
+	removable = map[string]bool{"where": true, "group": true, "having": true, "order": true, "limit": true}
+	setOps    = map[string]bool{"union": true, "except": true, "intersect": true}
+)
+
+// tokenize splits the statement into tokens: spaces, comments, quoted strings
+// and identifiers, words and punctuations. The tokens concatenate to the
+// statement.
+func tokenize(stmt string) []string {
+	var tokens []string
+	for i := 0; i < len(stmt); {
+		j := i + 1
+		switch c := stmt[i]; {
+		case isSpace(c):
+			for j < len(stmt) && isSpace(stmt[j]) {
+				j++
+			}
+		case c == '\'' || c == '"' || c == '`':
+			for j < len(stmt) && stmt[j] != c {
+				if stmt[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			if j < len(stmt) {
+				j++
+			}
+		case strings.HasPrefix(stmt[i:], "/*"):
+			if end := strings.Index(stmt[i+2:], "*/"); end >= 0 {
+				j = i + 2 + end + 2
+			} else {
+				j = len(stmt)
+			}
+		case c == '#' || strings.HasPrefix(stmt[i:], "-- "):
+			if end := strings.IndexByte(stmt[i:], '\n'); end >= 0 {
+				j = i + end
+			} else {
+				j = len(stmt)
+			}
+		case isWord(c):
+			for j < len(stmt) && isWord(stmt[j]) {
+				j++
+			}
+		}
+		if j > len(stmt) {
+			j = len(stmt)
+		}
+		tokens = append(tokens, stmt[i:j])
+		i = j
+	}
+	return tokens
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c == '$' || c == '@' || c == '.' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// item is an item of a list, which are the tokens [start, end) following the
+// separator tokens [sep, start).
+type item struct {
+	sep, start, end int
+}
+
+// list is a list of items in a statement separated by commas or AND/OR, e.g.
+// the select list, the rows of VALUES, the arguments of a function or the
+// predicates of WHERE.
+type list struct {
+	tokens []string
+	items  []item
+}
+
+// keep returns the statement with only the kept items of the list, the kept
+// indexes are in ascending order.
+func (l *list) keep(kept []int) string {
+	first, last := l.items[0], l.items[len(l.items)-1]
+	stmt := strings.Join(l.tokens[:first.start], "")
+	for j, k := range kept {
+		it := l.items[k]
+		if j == 0 {
+			// The first kept item takes the place of the first item, without the
+			// spaces which followed the removed separator
+			text := strings.Join(l.tokens[it.start:it.end], "")
+			if k > 0 {
+				text = strings.TrimLeft(text, " \t\r\n")
+			}
+			stmt = concat(stmt, text)
+		} else {
+			stmt = concat(stmt, strings.Join(l.tokens[it.sep:it.end], ""))
+		}
+	}
+	return strings.TrimSpace(concat(stmt, strings.Join(l.tokens[last.end:], "")))
+}
+
+// concat concatenates the parts of a statement without doubling the spaces
+// where something is removed between them.
+func concat(a, b string) string {
+	if a != "" && b != "" && isSpace(a[len(a)-1]) && isSpace(b[0]) {
+		return a + strings.TrimLeft(b, " \t\r\n")
+	}
+	return a + b
+}
+
+// clause is a clause of a statement in the tokens [start, end), whose keyword
+// is the first token.
+type clause struct {
+	keyword    string
+	start, end int
+}
+
+// structure is the clauses and lists of a statement at all levels of parentheses.
+type structure struct {
+	tokens  []string
+	clauses []clause
+	lists   []*list
+}
+
+func parse(stmt string) *structure {
+	s := &structure{tokens: tokenize(stmt)}
+	s.group(0, len(s.tokens))
+	return s
+}
+
+// group parses the tokens [from, to) which are at the same level of
+// parentheses, the nested levels are parsed recursively after it so that the
+// outer clauses and lists come first.
+func (s *structure) group(from, to int) {
+	var segments []clause
+	var nested [][2]int
+	segment := clause{start: from}
+	for i := from; i < to; i++ {
+		token := s.tokens[i]
+		if token == "(" {
+			end := s.match(i, to)
+			nested = append(nested, [2]int{i + 1, end})
+			i = end
+			continue
+		}
+		word := strings.ToLower(token)
+		if clauseKeywords[word] && i > from {
+			segment.end = i
+			segments = append(segments, segment)
+			segment = clause{keyword: word, start: i}
+		} else if clauseKeywords[word] {
+			segment.keyword = word
+		}
+	}
+	segment.end = to
+	segments = append(segments, segment)
+
+	for _, seg := range segments {
+		if removable[seg.keyword] {
+			s.clauses = append(s.clauses, seg)
+		}
+		if setOps[seg.keyword] {
+			s.clauses = append(s.clauses, clause{keyword: seg.keyword, start: seg.start, end: to})
+		}
+		s.split(s.contentStart(seg), seg.end, func(token string) bool { return token == "," })
+		s.split(s.contentStart(seg), seg.end, func(token string) bool {
+			word := strings.ToLower(token)
+			return word == "and" || word == "or"
+		})
+	}
+	for _, g := range nested {
+		s.group(g[0], g[1])
+	}
+}
+
+// match returns the index of the parenthesis closing the one at i, or to if unclosed.
+func (s *structure) match(i, to int) int {
+	depth := 0
+	for j := i; j < to; j++ {
+		switch s.tokens[j] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return to
+}
+
+// contentStart returns the index of the first token after the keyword of the
+// clause and its continuations.
+func (s *structure) contentStart(seg clause) int {
+	i := seg.start
+	if seg.keyword == "" {
+		return i
+	}
+	for i++; i < seg.end; i++ {
+		token := strings.ToLower(s.tokens[i])
+		if !isSpace(token[0]) && !continuations[token] {
+			break
+		}
+	}
+	return i
+}
+
+// split adds the list of items in the tokens [from, to) separated by the
+// separators at the same level, if there are at least two items.
+func (s *structure) split(from, to int, separator func(string) bool) {
+	l := &list{tokens: s.tokens}
+	it := item{sep: from, start: from}
+	for i := from; i < to; i++ {
+		if s.tokens[i] == "(" {
+			i = s.match(i, to)
+			continue
+		}
+		if separator(s.tokens[i]) {
+			it.end = i
+			l.items = append(l.items, it)
+			it = item{sep: i, start: i + 1}
+		}
+	}
+	it.end = to
+	l.items = append(l.items, it)
+	if len(l.items) >= 2 {
+		s.lists = append(s.lists, l)
+	}
+}
+
+// removeClauses returns the variants of the statement with a removable clause
+// removed, e.g. WHERE, ORDER BY, LIMIT or the rest of a UNION.
+func removeClauses(stmt string) []string {
+	s := parse(stmt)
+	var variants []string
+	for _, c := range s.clauses {
+		variants = append(variants, strings.TrimSpace(concat(strings.Join(s.tokens[:c.start], ""), strings.Join(s.tokens[c.end:], ""))))
+	}
+	return variants
+}
+
+// lists returns the lists of the statement.
+func lists(stmt string) []*list {
+	return parse(stmt).lists
+}
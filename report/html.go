@@ -0,0 +1,279 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/pingcap/tidiff/diff"
+	"github.com/pingcap/tidiff/script"
+)
+
+// HTML collects the results of statements for a self-contained HTML report,
+// which renders the results of all backends side by side with the differences
+// highlighted.
+type HTML struct {
+	statements []*htmlStatement
+	summary    script.Summary
+}
+
+type htmlStatement struct {
+	Index    int
+	Location string
+	Query    string
+	Rendered string
+	Seed     string
+	Status   string
+	Error    string
+	Backends []*htmlBackend
+}
+
+type htmlBackend struct {
+	Name      string
+	Address   string
+	Reference bool
+	Error     string
+	Columns   []htmlCell
+	Rows      [][]htmlCell
+	Stat      htmlCell
+	Duration  string
+	Warnings  []htmlCell
+	Verdict   string
+}
+
+type htmlCell struct {
+	Text    string
+	Null    bool
+	Differs bool
+}
+
+func NewHTML() *HTML {
+	return &HTML{}
+}
+
+// Add adds the result of a statement to the report.
+func (h *HTML) Add(r *script.Result) {
+	h.summary.Add(r)
+	s := NewStatement(r)
+	hs := &htmlStatement{
+		Index:    len(h.statements) + 1,
+		Location: r.Statement.Location(),
+		Query:    s.Query,
+		Status:   s.Status,
+		Error:    s.Error,
+	}
+	if s.Rendered != s.Query {
+		hs.Rendered = s.Rendered
+	}
+	if s.Seed != nil {
+		hs.Seed = fmt.Sprint(*s.Seed)
+	}
+	h.statements = append(h.statements, hs)
+	if r.Comparison == nil {
+		return
+	}
+
+	// The reference is highlighted against the first backend which differs from it
+	c := r.Comparison
+	var refVerdict *Verdict
+	for _, b := range s.Backends {
+		if b.Verdict != nil && b.Verdict.Differs {
+			refVerdict = b.Verdict
+			break
+		}
+	}
+	var refStat bool
+	for _, b := range s.Backends {
+		refStat = refStat || (b.Verdict != nil && b.Verdict.Statistics)
+	}
+	for i, b := range s.Backends {
+		hb := &htmlBackend{
+			Name:      b.Name,
+			Address:   b.Address,
+			Reference: b.Reference,
+			Duration:  fmt.Sprintf("%.3f sec", b.Duration),
+		}
+		if b.Error != nil {
+			hb.Error = c.Results[i].Error.Error()
+		}
+		hb.Stat = htmlCell{Text: c.Results[i].Summary(), Differs: refStat}
+
+		v, side := b.Verdict, otherSide
+		if b.Reference {
+			v, side = refVerdict, refSide
+		} else {
+			hb.Verdict = v.Description
+			hb.Stat.Differs = v.Statistics
+		}
+		rows, cols, warnings := marked(v, side)
+		for j, name := range b.Columns {
+			hb.Columns = append(hb.Columns, htmlCell{Text: name, Differs: cols[j]})
+		}
+		for j, row := range b.Rows {
+			cells := make([]htmlCell, len(row))
+			for k, cell := range row {
+				cells[k] = htmlCell{Text: "NULL", Null: true, Differs: rows[j][-1] || rows[j][k]}
+				if cell != nil {
+					cells[k].Text, cells[k].Null = *cell, false
+				}
+			}
+			hb.Rows = append(hb.Rows, cells)
+		}
+		for j, w := range b.Warnings {
+			hb.Warnings = append(hb.Warnings, htmlCell{Text: w.String(), Differs: warnings[j]})
+		}
+		hs.Backends = append(hs.Backends, hb)
+	}
+}
+
+const (
+	refSide = iota
+	otherSide
+)
+
+// marked returns the rows (-1 for the whole row), columns and warnings to
+// highlight in a side of the verdict.
+func marked(v *Verdict, side int) (map[int]map[int]bool, map[int]bool, map[int]bool) {
+	rows, cols, warnings := map[int]map[int]bool{}, map[int]bool{}, map[int]bool{}
+	if v == nil {
+		return rows, cols, warnings
+	}
+	mark := func(row, col int) {
+		if rows[row] == nil {
+			rows[row] = map[int]bool{}
+		}
+		rows[row][col] = true
+	}
+	index := func(row diff.Row) int {
+		if side == refSide {
+			return row.Ref
+		}
+		return row.Other
+	}
+	for _, col := range v.Columns {
+		cols[col] = true
+	}
+	for _, m := range v.Metadata {
+		cols[m.Column] = true
+	}
+	for _, row := range v.Rows {
+		if index(row) < 0 {
+			continue
+		}
+		if row.Kind != diff.Changed {
+			mark(index(row), -1)
+		}
+		for _, col := range row.Cells {
+			mark(index(row), col)
+		}
+	}
+	for _, row := range v.Warnings {
+		if index(row) >= 0 {
+			warnings[index(row)] = true
+		}
+	}
+	return rows, cols, warnings
+}
+
+// Write writes the HTML report.
+func (h *HTML) Write(w io.Writer) error {
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Generated":  time.Now().Format("2006-01-02 15:04:05"),
+		"Summary":    &h.summary,
+		"Statements": h.statements,
+	})
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tidiff report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #24292e; }
+header { position: sticky; top: 0; background: #f6f8fa; border-bottom: 1px solid #d1d5da; padding: 12px 24px; }
+header h1 { font-size: 20px; margin: 0 0 8px 0; }
+.count { display: inline-block; margin-right: 16px; }
+main { padding: 12px 24px; }
+.statement { border: 1px solid #d1d5da; border-radius: 4px; margin-bottom: 16px; }
+.statement > .title { padding: 8px 12px; background: #f6f8fa; border-bottom: 1px solid #d1d5da; }
+.status { font-weight: bold; text-transform: uppercase; font-size: 12px; margin-right: 8px; }
+.matched .status, .count.matched { color: #22863a; }
+.differed .status, .count.differed { color: #cb2431; }
+.errored .status, .count.errored { color: #b08800; }
+.suppressed .status, .count.suppressed { color: #6a737d; }
+.location { color: #6a737d; font-size: 12px; }
+pre { margin: 4px 0 0 0; white-space: pre-wrap; font-size: 13px; }
+.backends { display: flex; overflow-x: auto; }
+.backend { flex: 1 0 auto; padding: 8px 12px; border-right: 1px solid #eaecef; }
+.backend:last-child { border-right: none; }
+.backend h2 { font-size: 14px; margin: 0 0 6px 0; }
+.address, .duration { color: #6a737d; font-weight: normal; font-size: 12px; }
+table { border-collapse: collapse; font-family: monospace; font-size: 13px; }
+th, td { border: 1px solid #d1d5da; padding: 2px 6px; text-align: left; vertical-align: top; white-space: pre; }
+th { background: #f6f8fa; }
+.null { color: #6a737d; font-style: italic; }
+.reference .differs { background: #ffdce0; }
+.other .differs { background: #cdffd8; }
+.error { color: #cb2431; font-family: monospace; font-size: 13px; }
+.stat, .warning { font-family: monospace; font-size: 13px; margin-top: 4px; }
+.verdict { color: #b08800; font-family: monospace; font-size: 13px; margin-top: 4px; }
+body.only-differed .statement:not(.differed) { display: none; }
+body.only-errored .statement:not(.errored) { display: none; }
+body.only-suppressed .statement:not(.suppressed) { display: none; }
+</style>
+</head>
+<body>
+<header>
+<h1>tidiff report</h1>
+<span class="count">{{.Summary.Total}} statements</span>
+<span class="count matched">{{.Summary.Matched}} matched</span>
+<span class="count differed">{{.Summary.Differed}} differed</span>
+<span class="count errored">{{.Summary.Errored}} errored</span>
+<span class="count suppressed">{{.Summary.Suppressed}} suppressed</span>
+<span class="count">Show
+<select id="filter" onchange="document.body.className = this.value">
+<option value="">all statements</option>
+<option value="only-differed">differed statements only</option>
+<option value="only-errored">errored statements only</option>
+<option value="only-suppressed">suppressed statements only</option>
+</select>
+</span>
+<span class="count location">Generated at {{.Generated}}</span>
+</header>
+<main>
+{{range .Statements}}
+<div class="statement {{.Status}}" id="statement-{{.Index}}">
+<div class="title">
+<span class="status">{{.Status}}</span><span class="location">#{{.Index}} {{.Location}}{{if .Seed}} seed {{.Seed}}{{end}}</span>
+<pre>{{.Query}}</pre>
+{{if .Rendered}}<pre class="location">{{.Rendered}}</pre>{{end}}
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+</div>
+{{if .Backends}}
+<div class="backends">
+{{range .Backends}}
+<div class="backend {{if .Reference}}reference{{else}}other{{end}}">
+<h2>{{.Name}} <span class="address">{{.Address}}{{if .Reference}} (reference){{end}}</span></h2>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .Columns}}
+<table>
+<tr>{{range .Columns}}<th{{if .Differs}} class="differs"{{end}}>{{.Text}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td class="{{if .Null}}null{{end}}{{if .Differs}} differs{{end}}">{{.Text}}</td>{{end}}</tr>
+{{end}}
+</table>
+{{end}}
+{{if not .Error}}<div class="stat"><span{{if .Stat.Differs}} class="differs"{{end}}>{{.Stat.Text}}</span> <span class="duration">({{.Duration}})</span></div>{{end}}
+{{range .Warnings}}<div class="warning{{if .Differs}} differs{{end}}">{{.Text}}</div>{{end}}
+{{if .Verdict}}<div class="verdict">-- {{.Verdict}}</div>{{end}}
+</div>
+{{end}}
+</div>
+{{end}}
+</div>
+{{end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+func TestHTML(t *testing.T) {
+	a := executor.NewBackend("MySQL", &executor.Config{Host: "127.0.0.1", Port: 3306})
+	b := executor.NewBackend("TiDB", &executor.Config{Host: "127.0.0.1", Port: 4000})
+	exec := executor.NewExecutor(a, b)
+	query := "select a, b from t order by a"
+	results := []*executor.QueryResult{
+		{Backend: a, Rendered: query, Columns: []string{"a", "b"}, Rows: [][]sql.NullString{
+			{{String: "1", Valid: true}, {String: "<x>", Valid: true}},
+			{{String: "2", Valid: true}, {}},
+		}},
+		{Backend: b, Rendered: query, Columns: []string{"a", "b"}, Rows: [][]sql.NullString{
+			{{String: "1", Valid: true}, {String: "<x>", Valid: true}},
+			{{String: "2", Valid: true}, {String: "y", Valid: true}},
+		}},
+	}
+	h := NewHTML()
+	h.Add(&script.Result{
+		Statement:  &script.Statement{Text: query, File: "a.sql", Line: 1},
+		Comparison: exec.Compare(results),
+	})
+	var buf bytes.Buffer
+	if err := h.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, expected := range []string{
+		`<span class="count differed">1 differed</span>`,
+		`<div class="statement differed" id="statement-1">`,
+		`<td class="">&lt;x&gt;</td>`,
+		`<td class="null differs">NULL</td>`,
+		`<td class=" differs">y</td>`,
+		`<div class="verdict">-- 1 changed rows; row 2 column b: NULL vs &#39;y&#39;</div>`,
+	} {
+		if !strings.Contains(report, expected) {
+			t.Fatalf("expected %s in %s", expected, report)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package report converts the results of statements into machine-readable and
+// shareable reports.
+package report
+
+import (
+	"github.com/pingcap/tidiff/baseline"
+	"github.com/pingcap/tidiff/diff"
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+// Statement is the result of a statement executed in all backends, which is
+// encoded as a JSON object.
+type Statement struct {
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Query is the statement as written, and Rendered is the executed one
+	// which differs if the statement is a template.
+	Query    string `json:"query"`
+	Rendered string `json:"rendered,omitempty"`
+	// Seed is the seed of the random values if the statement is a template, or
+	// the seed which generated the statement.
+	Seed *int64 `json:"seed,omitempty"`
+	// Status is one of matched, differed, errored and suppressed.
+	Status string `json:"status"`
+	// Error is the reason why the statement can't be executed at all, e.g. an
+	// invalid template.
+	Error     string     `json:"error,omitempty"`
+	Unordered bool       `json:"unordered,omitempty"`
+	Backends  []*Backend `json:"backends,omitempty"`
+}
+
+// Backend is the result of a statement in a backend.
+type Backend struct {
+	Name         string             `json:"name"`
+	Address      string             `json:"address"`
+	Reference    bool               `json:"reference,omitempty"`
+	Error        *baseline.Error    `json:"error,omitempty"`
+	Columns      []string           `json:"columns,omitempty"`
+	ColumnTypes  []diff.ColumnType  `json:"column_types,omitempty"`
+	Rows         baseline.Rows      `json:"rows,omitempty"`
+	AffectedRows int64              `json:"affected_rows"`
+	LastInsertID int64              `json:"last_insert_id"`
+	WarningCount int                `json:"warning_count"`
+	Warnings     []executor.Warning `json:"warnings,omitempty"`
+	// Duration is the execution time in seconds.
+	Duration float64 `json:"duration"`
+	// Verdict is how the backend compares with the reference, which is nil
+	// for the reference.
+	Verdict *Verdict `json:"verdict,omitempty"`
+}
+
+// Verdict is how a backend compares with the reference.
+type Verdict struct {
+	Differs bool `json:"differs"`
+	// SuppressedBy is the name of the suppression rule which suppressed the differences.
+	SuppressedBy string `json:"suppressed_by,omitempty"`
+	// Description describes the differences briefly, e.g. `1 changed rows`.
+	Description string `json:"description,omitempty"`
+	// Statistics reports whether the statistics (e.g. affected rows) differ.
+	Statistics bool `json:"statistics,omitempty"`
+	// Columns are the indexes of columns whose names are different.
+	Columns  []int             `json:"columns,omitempty"`
+	Metadata []diff.ColumnDiff `json:"metadata,omitempty"`
+	// Rows and Warnings are the rows of the result set and warnings which
+	// differ from the reference, the equal rows are omitted.
+	Rows     []diff.Row `json:"rows,omitempty"`
+	Warnings []diff.Row `json:"warnings,omitempty"`
+}
+
+// NewStatement converts the result of a statement.
+func NewStatement(r *script.Result) *Statement {
+	s := &Statement{
+		File:   r.Statement.File,
+		Line:   r.Statement.Line,
+		Query:  r.Statement.Text,
+		Status: r.Status().String(),
+	}
+	if r.Seed != nil {
+		seed := *r.Seed
+		s.Seed = &seed
+	}
+	if r.Err != nil {
+		s.Error = r.Err.Error()
+		return s
+	}
+	c := r.Comparison
+	ref := c.Results[c.Reference]
+	s.Rendered = ref.Rendered
+	if ref.Template {
+		seed := ref.Seed
+		s.Seed = &seed
+	}
+	s.Unordered = c.Unordered
+	for i, result := range c.Results {
+		b := &Backend{
+			Name:         result.Backend.Name,
+			Address:      result.Backend.Config.Address(),
+			Reference:    i == c.Reference,
+			Error:        baseline.NewError(result.Error),
+			Columns:      result.Columns,
+			ColumnTypes:  result.ColumnTypes,
+			Rows:         baseline.NewRows(result.Rows),
+			AffectedRows: result.AffectedRows,
+			LastInsertID: result.LastInsertID,
+			WarningCount: result.WarningCount,
+			Warnings:     result.Warnings,
+			Duration:     result.Duration().Seconds(),
+		}
+		if i != c.Reference {
+			b.Verdict = &Verdict{
+				Differs:      c.Differs[i],
+				SuppressedBy: c.SuppressedBy[i],
+				Description:  c.Verdicts[i],
+				Statistics:   c.StatisticsDiffer[i],
+			}
+			if d := c.Diffs[i]; d != nil {
+				b.Verdict.Columns, b.Verdict.Metadata, b.Verdict.Rows = d.Columns, d.Metadata, changes(d)
+			}
+			if d := c.WarningDiffs[i]; d != nil {
+				b.Verdict.Warnings = changes(d)
+			}
+		}
+		s.Backends = append(s.Backends, b)
+	}
+	return s
+}
+
+// changes returns the rows of the diff which aren't equal.
+func changes(d *diff.Result) []diff.Row {
+	var rows []diff.Row
+	for _, row := range d.Rows {
+		if row.Kind != diff.Equal {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
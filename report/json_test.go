@@ -0,0 +1,65 @@
+package report
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+func TestNewStatement(t *testing.T) {
+	a := executor.NewBackend("MySQL", &executor.Config{Host: "127.0.0.1", Port: 3306})
+	b := executor.NewBackend("TiDB", &executor.Config{Host: "127.0.0.1", Port: 4000})
+	c := executor.NewBackend("MariaDB", &executor.Config{Host: "127.0.0.1", Port: 3307})
+	exec := executor.NewExecutor(a, b, c)
+	query := "select a from t order by a"
+	results := []*executor.QueryResult{
+		{Backend: a, Rendered: query, Columns: []string{"a"}, Rows: [][]sql.NullString{{{String: "1", Valid: true}}, {{}}}},
+		{Backend: b, Rendered: query, Columns: []string{"a"}, Rows: [][]sql.NullString{{{String: "1", Valid: true}}, {{String: "", Valid: true}}}},
+		{Backend: c, Rendered: query, Error: &mysql.MySQLError{Number: 1146, SQLState: [5]byte{'4', '2', 'S', '0', '2'}, Message: "no such table"}},
+	}
+	result := &script.Result{
+		Statement:  &script.Statement{Text: query, File: "a.sql", Line: 3},
+		Comparison: exec.Compare(results),
+	}
+	data, err := json.Marshal(NewStatement(result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"file":"a.sql","line":3`,
+		`"status":"differed"`,
+		`"rows":[["1"],[null]]`,
+		`"verdict":{"differs":true,"description":"1 changed rows; row 2 column a: NULL vs ''","rows":[{"kind":"changed","ref":1,"other":1,"cells":[0]}]}`,
+		`"error":{"code":1146,"sqlstate":"42S02","message":"no such table"}`,
+		`"verdict":{"differs":true,"description":"MySQL success vs MariaDB error 1146"}`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Fatalf("expected %s in %s", expected, data)
+		}
+	}
+}
+
+func TestNewStatementSeed(t *testing.T) {
+	seed := int64(42)
+	result := &script.Result{
+		Statement: &script.Statement{Text: "select", File: "fuzz", Line: 3},
+		Err:       errors.New("invalid connection"),
+		Seed:      &seed,
+	}
+	if result.Location() != "fuzz:3 seed 42" {
+		t.Fatalf("unexpected location %s", result.Location())
+	}
+	data, err := json.Marshal(NewStatement(result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"file":"fuzz","line":3,"query":"select","seed":42`) {
+		t.Fatalf("expected the seed in %s", data)
+	}
+}
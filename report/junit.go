@@ -0,0 +1,179 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidiff/script"
+)
+
+// JUnit collects the results of statements as the test cases of a JUnit XML
+// report, which are grouped into test suites by file.
+type JUnit struct {
+	suites []*junitSuite
+	index  map[string]*junitSuite
+}
+
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Errors   int           `xml:"errors,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     float64       `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Cases    []*junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+}
+
+// junitProblem is the problem of a test case, the content is escaped as text
+// rather than CDATA since the results may contain `]]>` or control characters
+// which are invalid in XML.
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+func NewJUnit() *JUnit {
+	return &JUnit{index: map[string]*junitSuite{}}
+}
+
+// Add adds the result of a statement as a test case, the differed statements
+// are failures, the errored ones are errors and the suppressed ones are skipped.
+func (j *JUnit) Add(r *script.Result) {
+	file := r.Statement.File
+	suite, ok := j.index[file]
+	if !ok {
+		suite = &junitSuite{Name: file}
+		j.suites = append(j.suites, suite)
+		j.index[file] = suite
+	}
+
+	tc := &junitCase{
+		Name:      fmt.Sprintf("%s %s", r.Location(), abbreviate(r.Statement.Text, 80)),
+		ClassName: file,
+		Time:      elapsed(r).Seconds(),
+	}
+	switch r.Status() {
+	case script.Differed:
+		tc.Failure = &junitProblem{Message: message(r), Type: "differed", Content: plain(r)}
+		suite.Failures++
+	case script.Errored:
+		tc.Error = &junitProblem{Message: message(r), Type: "errored", Content: plain(r)}
+		suite.Errors++
+	case script.Suppressed:
+		tc.Skipped = &junitProblem{Message: message(r), Type: "suppressed", Content: plain(r)}
+		suite.Skipped++
+	}
+	suite.Tests++
+	suite.Time += tc.Time
+	suite.Cases = append(suite.Cases, tc)
+}
+
+// Write writes the XML report.
+func (j *JUnit) Write(w io.Writer) error {
+	report := &junitSuites{Suites: j.suites}
+	for _, suite := range j.suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Time += suite.Time
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// elapsed returns the longest execution time of the statement in all backends.
+func elapsed(r *script.Result) time.Duration {
+	var d time.Duration
+	if r.Comparison == nil {
+		return d
+	}
+	for _, result := range r.Comparison.Results {
+		if result.Duration() > d {
+			d = result.Duration()
+		}
+	}
+	return d
+}
+
+// message describes why the statement differed, errored or was suppressed in a line.
+func message(r *script.Result) string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	c := r.Comparison
+	var verdicts []string
+	for i, result := range c.Results {
+		if c.Differs[i] {
+			verdicts = append(verdicts, fmt.Sprintf("%s: %s", result.Backend.Name, c.Verdicts[i]))
+		}
+	}
+	if len(verdicts) == 0 {
+		for i, result := range c.Results {
+			if c.SuppressedBy[i] != "" {
+				verdicts = append(verdicts, fmt.Sprintf("%s: %s", result.Backend.Name, c.Verdicts[i]))
+			}
+		}
+	}
+	if len(verdicts) > 0 {
+		return strings.Join(verdicts, "; ")
+	}
+	// Failed consistently in all backends
+	for _, result := range c.Results {
+		if result.Error != nil {
+			return result.Error.Error()
+		}
+	}
+	return ""
+}
+
+// plain renders the results of all backends like the command line mode without colors.
+func plain(r *script.Result) string {
+	if r.Err != nil {
+		return r.Statement.Text
+	}
+	return r.Comparison.Format(r.Statement.Text, fmt.Sprint, fmt.Sprint)
+}
+
+// abbreviate returns the first line of the text, which is truncated to n characters.
+func abbreviate(text string, n int) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i] + " ..."
+	}
+	if runes := []rune(text); len(runes) > n {
+		text = string(runes[:n]) + "..."
+	}
+	return text
+}
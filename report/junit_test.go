@@ -0,0 +1,88 @@
+package report
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+func TestJUnit(t *testing.T) {
+	a := executor.NewBackend("MySQL", &executor.Config{Host: "127.0.0.1", Port: 3306})
+	b := executor.NewBackend("TiDB", &executor.Config{Host: "127.0.0.1", Port: 4000})
+	exec := executor.NewExecutor(a, b)
+	result := func(file string, line int, x, y string) *script.Result {
+		query := "select 1"
+		results := []*executor.QueryResult{
+			{Backend: a, Rendered: query, Columns: []string{"1"}, Rows: [][]sql.NullString{{{String: x, Valid: true}}}},
+			{Backend: b, Rendered: query, Columns: []string{"1"}, Rows: [][]sql.NullString{{{String: y, Valid: true}}}},
+		}
+		return &script.Result{
+			Statement:  &script.Statement{Text: query, File: file, Line: line},
+			Comparison: exec.Compare(results),
+		}
+	}
+
+	j := NewJUnit()
+	j.Add(result("a.sql", 1, "1", "1"))
+	j.Add(result("a.sql", 2, "1", "2"))
+	j.Add(&script.Result{Statement: &script.Statement{Text: "! {{", File: "a.sql", Line: 3}, Err: errors.New("bad template")})
+	j.Add(result("b.sql", 1, "1", "1"))
+	var buf bytes.Buffer
+	if err := j.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Tests != 4 || report.Failures != 1 || report.Errors != 1 || len(report.Suites) != 2 {
+		t.Fatalf("unexpected report %s", buf.String())
+	}
+	suite := report.Suites[0]
+	if suite.Name != "a.sql" || len(suite.Cases) != 3 {
+		t.Fatalf("unexpected suite %s", buf.String())
+	}
+	if suite.Cases[0].Failure != nil || suite.Cases[0].Error != nil {
+		t.Fatalf("unexpected problem of matched statement %s", buf.String())
+	}
+	if f := suite.Cases[1].Failure; f == nil || f.Message != "TiDB: 1 changed rows" {
+		t.Fatalf("unexpected failure %s", buf.String())
+	}
+	if e := suite.Cases[2].Error; e == nil || e.Message != "bad template" {
+		t.Fatalf("unexpected error %s", buf.String())
+	}
+}
+
+func TestJUnitInvalidText(t *testing.T) {
+	a := executor.NewBackend("MySQL", &executor.Config{Host: "127.0.0.1", Port: 3306})
+	b := executor.NewBackend("TiDB", &executor.Config{Host: "127.0.0.1", Port: 4000})
+	query := "select c from t"
+	results := []*executor.QueryResult{
+		{Backend: a, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: "a]]>b", Valid: true}}}},
+		{Backend: b, Rendered: query, Columns: []string{"c"}, Rows: [][]sql.NullString{{{String: "\x00\x1b", Valid: true}}}},
+	}
+	j := NewJUnit()
+	j.Add(&script.Result{
+		Statement:  &script.Statement{Text: query, File: "a.sql", Line: 1},
+		Comparison: executor.NewExecutor(a, b).Compare(results),
+	})
+	var buf bytes.Buffer
+	if err := j.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report %v: %s", err, buf.String())
+	}
+	content := report.Suites[0].Cases[0].Failure.Content
+	if !strings.Contains(content, "a]]>b") || !strings.Contains(content, "\ufffd\ufffd") {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var runCommand = &cli.Command{
+	Name:      "run",
+	Usage:     "Execute a SQL script statement by statement and report the differences",
+	ArgsUsage: "file.sql|-",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Print the results of all statements instead of the differed and errored ones only",
+		},
+	},
+	Action: runScript,
+}
+
+// openExecutor creates an executor with the global flags and opens it.
+func openExecutor(ctx *cli.Context) (*executor.Executor, error) {
+	exec, err := newExecutor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(exec.Backends) < 2 {
+		return nil, errors.New("at least two backends are required")
+	}
+	if err := exec.Open(executor.DefaultRetryCnt); err != nil {
+		return nil, err
+	}
+	return exec, nil
+}
+
+// openScript opens the script specified by the only argument, `-` for stdin.
+// It returns the name of the script used in the locations of statements.
+func openScript(ctx *cli.Context) (string, io.ReadCloser, error) {
+	if ctx.NArg() != 1 {
+		return "", nil, errors.New("a script file is required")
+	}
+	path := ctx.Args().First()
+	if path == "-" {
+		return "stdin", ioutil.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, file, nil
+}
+
+func runScript(ctx *cli.Context) error {
+	path, r, err := openScript(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	exec, err := openExecutor(ctx)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+	return execScript(ctx, exec, path, r)
+}
+
+// execScript executes the statements read from r in the opened executor and
+// prints the summary, the statements are executed as soon as they are read so
+// that r can be a stream (e.g. the output of a generator).
+func execScript(ctx *cli.Context, exec *executor.Executor, path string, r io.Reader) error {
+	out := newOutput(ctx)
+	runner := &script.Runner{
+		Executor: exec,
+		Handler:  out.handle,
+	}
+	summary, err := runner.Run(script.NewScanner(path, r))
+	if err != nil {
+		return err
+	}
+	if exec.Renders() > 0 {
+		fmt.Fprintf(os.Stderr, "templates rendered from seed %d, replay by --seed %d\n", exec.Seed, exec.Seed)
+	}
+	return out.finish(summary)
+}
+
+// isTerminal reports whether the file is a terminal rather than a pipe or a regular file.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// printResult returns a handler which prints the results of statements like the
+// command line mode, the matched and suppressed statements are skipped unless verbose.
+func printResult(verbose bool) func(*script.Result) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	return func(result *script.Result) {
+		status := result.Status()
+		if (status == script.Matched || status == script.Suppressed) && !verbose {
+			return
+		}
+		fmt.Println(yellow(fmt.Sprintf("-- %s %s", result.Location(), status)))
+		if result.Err != nil {
+			fmt.Println(result.Statement.Text)
+			fmt.Println(red(result.Err.Error()) + "\n")
+			return
+		}
+		fmt.Print(result.Comparison.Format(result.Statement.Text, red, green))
+	}
+}
@@ -0,0 +1,155 @@
+package script
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pingcap/tidiff/executor"
+)
+
+// Status is the status of a statement executed in all backends.
+type Status int
+
+const (
+	// Matched means the results of all backends are the same.
+	Matched Status = iota
+	// Differed means any backend differs from the reference.
+	Differed
+	// Errored means the statement failed consistently in all backends, or it
+	// can't be executed at all (e.g. invalid directives).
+	Errored
+	// Suppressed means the differences of the statement are all known and
+	// suppressed by the suppression rules.
+	Suppressed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Matched:
+		return "matched"
+	case Differed:
+		return "differed"
+	case Errored:
+		return "errored"
+	case Suppressed:
+		return "suppressed"
+	}
+	return "unknown"
+}
+
+// Result is the result of a statement executed in all backends.
+type Result struct {
+	Statement *Statement
+	// Comparison is nil if the statement can't be executed.
+	Comparison *executor.Comparison
+	// Err is why the statement can't be executed, or fails the test even if
+	// the results are the same, e.g. succeeding while an error is expected.
+	Err error
+	// Expected reports whether the errors are expected by the test (e.g. the
+	// `--error` command of mysqltest), which makes the failed statement match.
+	Expected bool
+	// Seed is the seed which generated the statement (e.g. the queries of fuzz
+	// and the rows of fill), nil if the statement isn't generated.
+	Seed *int64
+}
+
+// Location returns the location of the statement, which is followed by the
+// seed if the statement is generated.
+func (r *Result) Location() string {
+	if r.Seed != nil {
+		return fmt.Sprintf("%s seed %d", r.Statement.Location(), *r.Seed)
+	}
+	return r.Statement.Location()
+}
+
+func (r *Result) Status() Status {
+	switch {
+	case r.Err != nil:
+		return Errored
+	case r.Comparison.Diff:
+		return Differed
+	case r.Comparison.Suppressed:
+		return Suppressed
+	case r.Comparison.Failed() && !r.Expected:
+		return Errored
+	}
+	return Matched
+}
+
+// Summary counts the statements by status.
+type Summary struct {
+	Matched    int `json:"matched"`
+	Differed   int `json:"differed"`
+	Errored    int `json:"errored"`
+	Suppressed int `json:"suppressed"`
+}
+
+func (s *Summary) Add(r *Result) {
+	switch r.Status() {
+	case Matched:
+		s.Matched++
+	case Differed:
+		s.Differed++
+	case Errored:
+		s.Errored++
+	case Suppressed:
+		s.Suppressed++
+	}
+}
+
+// Merge adds the counts of another summary, e.g. of another script.
+func (s *Summary) Merge(o *Summary) {
+	s.Matched += o.Matched
+	s.Differed += o.Differed
+	s.Errored += o.Errored
+	s.Suppressed += o.Suppressed
+}
+
+func (s *Summary) Total() int {
+	return s.Matched + s.Differed + s.Errored + s.Suppressed
+}
+
+// String describes the counts, the suppressed count is shown only if any.
+func (s *Summary) String() string {
+	str := fmt.Sprintf("%d statements: %d matched, %d differed, %d errored", s.Total(), s.Matched, s.Differed, s.Errored)
+	if s.Suppressed > 0 {
+		str += fmt.Sprintf(", %d suppressed", s.Suppressed)
+	}
+	return str
+}
+
+// Runner executes the statements of scripts in all backends one by one, and
+// hands the result of every statement to the handler.
+type Runner struct {
+	Executor *executor.Executor
+	Handler  func(*Result)
+}
+
+// Run executes all statements scanned by the scanner. Failed or differed
+// statements don't stop the script, only the errors of scanning do.
+func (r *Runner) Run(scanner *Scanner) (*Summary, error) {
+	summary := &Summary{}
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, err
+		}
+		result := r.Exec(stmt)
+		summary.Add(result)
+		if r.Handler != nil {
+			r.Handler(result)
+		}
+	}
+}
+
+// Exec executes a statement in all backends and compares the results.
+func (r *Runner) Exec(stmt *Statement) *Result {
+	results, err := r.Executor.Query(stmt.Text)
+	if err != nil {
+		return &Result{Statement: stmt, Err: err}
+	}
+	return &Result{Statement: stmt, Comparison: r.Executor.Compare(results)}
+}
@@ -0,0 +1,233 @@
+// Package script executes SQL scripts statement by statement in all backends.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Statement is a statement in a script.
+type Statement struct {
+	Text string
+	File string
+	// Line is the line number of the first character of the statement, or the
+	// number of the statement for the statements generated one per line, whose
+	// File names the source, e.g. fuzz or the table filled by fill.
+	Line int
+}
+
+// Location returns the location of the statement in the form `file:line`.
+func (s *Statement) Location() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Scanner splits a script into statements, which respects quoted strings and
+// identifiers, comments and the `DELIMITER` command of the mysql client. The
+// statements are read lazily so that it's able to scan a stream.
+type Scanner struct {
+	r         *bufio.Reader
+	file      string
+	delimiter string
+	line      int
+}
+
+// NewScanner returns a scanner reading from r, the file name is used in the
+// locations of statements.
+func NewScanner(file string, r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r), file: file, delimiter: ";", line: 1}
+}
+
+// Next returns the next statement, or io.EOF if there are no more statements.
+func (s *Scanner) Next() (*Statement, error) {
+	var buf strings.Builder
+	var line int
+	for {
+		// The DELIMITER command is only recognized at the beginning of a line
+		// before any statement text.
+		atLineStart := buf.Len() == 0 || strings.HasSuffix(buf.String(), "\n")
+		if atLineStart && s.peekKeyword("delimiter") && isBlank(buf.String()) {
+			// The line of the command, which readLine moves past unless it's the last line
+			line := s.line
+			text, err := s.readLine()
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			fields := strings.Fields(text)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%s:%d: missing delimiter", s.file, line)
+			}
+			s.delimiter = fields[1]
+			buf.Reset()
+			continue
+		}
+
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return s.statement(buf.String(), line)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() == 0 && unicode.IsSpace(rune(c)) {
+			if c == '\n' {
+				s.line++
+			}
+			continue
+		}
+		if buf.Len() == 0 {
+			line = s.line
+		}
+
+		switch {
+		case c == s.delimiter[0] && s.peek(s.delimiter[1:]):
+			_, _ = s.r.Discard(len(s.delimiter) - 1)
+			stmt, err := s.statement(buf.String(), line)
+			if err == io.EOF {
+				// Empty statement or comments only
+				buf.Reset()
+				continue
+			}
+			return stmt, err
+		case c == '\'' || c == '"' || c == '`':
+			buf.WriteByte(c)
+			if err := s.readQuoted(&buf, c); err != nil {
+				return nil, err
+			}
+		case c == '/' && s.peek("*"):
+			_, _ = s.r.Discard(1)
+			buf.WriteString("/*")
+			if err := s.readUntil(&buf, "*/"); err != nil {
+				return nil, err
+			}
+		case c == '#' || c == '-' && s.peekLineComment():
+			buf.WriteByte(c)
+			if err := s.readUntil(&buf, "\n"); err != nil {
+				return nil, err
+			}
+		default:
+			if c == '\n' {
+				s.line++
+			}
+			buf.WriteByte(c)
+		}
+	}
+}
+
+// statement returns the statement of text, or io.EOF if the text is blank.
+func (s *Scanner) statement(text string, line int) (*Statement, error) {
+	if isBlank(text) {
+		return nil, io.EOF
+	}
+	return &Statement{Text: strings.TrimRightFunc(text, unicode.IsSpace), File: s.file, Line: line}, nil
+}
+
+// peek reports whether the following bytes are s.
+func (s *Scanner) peek(str string) bool {
+	if str == "" {
+		return true
+	}
+	b, err := s.r.Peek(len(str))
+	return err == nil && string(b) == str
+}
+
+// peekKeyword reports whether the following bytes are the keyword followed by a space.
+func (s *Scanner) peekKeyword(keyword string) bool {
+	b, _ := s.r.Peek(len(keyword) + 1)
+	return len(b) == len(keyword)+1 && strings.EqualFold(string(b[:len(keyword)]), keyword) &&
+		(b[len(keyword)] == ' ' || b[len(keyword)] == '\t')
+}
+
+// peekLineComment reports whether the following bytes after a `-` start a `-- ` comment.
+func (s *Scanner) peekLineComment() bool {
+	b, err := s.r.Peek(2)
+	if err == io.EOF && len(b) == 1 {
+		return b[0] == '-'
+	}
+	return err == nil && b[0] == '-' && unicode.IsSpace(rune(b[1]))
+}
+
+func (s *Scanner) readLine() (string, error) {
+	text, err := s.r.ReadString('\n')
+	if err == nil {
+		s.line++
+	}
+	return text, err
+}
+
+// readQuoted reads until the closing quote, backslash escapes the next character
+// in strings but not in identifiers.
+func (s *Scanner) readQuoted(buf *strings.Builder, quote byte) error {
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c == '\n' {
+			s.line++
+		}
+		buf.WriteByte(c)
+		switch {
+		case c == quote:
+			return nil
+		case c == '\\' && quote != '`':
+			next, err := s.r.ReadByte()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if next == '\n' {
+				s.line++
+			}
+			buf.WriteByte(next)
+		}
+	}
+}
+
+// readUntil reads until the terminator (inclusive) or the end of input.
+func (s *Scanner) readUntil(buf *strings.Builder, terminator string) error {
+	for !strings.HasSuffix(buf.String(), terminator) {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c == '\n' {
+			s.line++
+		}
+		buf.WriteByte(c)
+	}
+	return nil
+}
+
+// isBlank reports whether the text only contains spaces and comments.
+func isBlank(text string) bool {
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(text, "/*"):
+			end := strings.Index(text[2:], "*/")
+			if end < 0 {
+				return true
+			}
+			text = text[end+4:]
+		case strings.HasPrefix(text, "#"), text == "--", strings.HasPrefix(text, "--") && unicode.IsSpace(rune(text[2])):
+			end := strings.Index(text, "\n")
+			if end < 0 {
+				return true
+			}
+			text = text[end+1:]
+		default:
+			return text == ""
+		}
+	}
+}
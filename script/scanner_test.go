@@ -0,0 +1,83 @@
+package script
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func scanAll(t *testing.T, text string) []Statement {
+	s := NewScanner("test.sql", strings.NewReader(text))
+	var stmts []Statement
+	for {
+		stmt, err := s.Next()
+		if err == io.EOF {
+			return stmts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmts = append(stmts, *stmt)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	text := `-- setup
+create table t (a varchar(10)); insert into t values ('a;b'), ("c\";d");
+/* tidiff:unordered */ select * from t -- trailing; comment
+where a = ';';
+
+select ` + "`a;`" + ` from t # comment;
+;
+DELIMITER //
+create procedure p()
+begin
+  select 1;
+end//
+delimiter ;
+select 2 /* a;
+b */;
+select 3`
+	expected := []Statement{
+		{Text: "-- setup\ncreate table t (a varchar(10))", File: "test.sql", Line: 1},
+		{Text: `insert into t values ('a;b'), ("c\";d")`, File: "test.sql", Line: 2},
+		{Text: "/* tidiff:unordered */ select * from t -- trailing; comment\nwhere a = ';'", File: "test.sql", Line: 3},
+		{Text: "select `a;` from t # comment;\n", File: "test.sql", Line: 6},
+		{Text: "create procedure p()\nbegin\n  select 1;\nend", File: "test.sql", Line: 9},
+		{Text: "select 2 /* a;\nb */", File: "test.sql", Line: 14},
+		{Text: "select 3", File: "test.sql", Line: 16},
+	}
+	stmts := scanAll(t, text)
+	for i := range stmts {
+		stmts[i].Text = strings.TrimSpace(stmts[i].Text)
+		expected[i].Text = strings.TrimSpace(expected[i].Text)
+	}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Fatalf("expected %q, got %q", expected, stmts)
+	}
+}
+
+func TestScannerBlank(t *testing.T) {
+	if stmts := scanAll(t, "  -- comment only\n;; /* x */ \n"); len(stmts) != 0 {
+		t.Fatalf("expected no statements, got %q", stmts)
+	}
+}
+
+func TestScannerMissingDelimiter(t *testing.T) {
+	cases := map[string]string{
+		"select 1;\n\ndelimiter \nselect 2;": "test.sql:3: missing delimiter",
+		"select 1;\n\ndelimiter \n":          "test.sql:3: missing delimiter",
+		"select 1;\n\ndelimiter ":            "test.sql:3: missing delimiter",
+	}
+	for text, expected := range cases {
+		s := NewScanner("test.sql", strings.NewReader(text))
+		var err error
+		for err == nil {
+			_, err = s.Next()
+		}
+		if err.Error() != expected {
+			t.Errorf("%q: expected %q, got %q", text, expected, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pingcap/tidiff/script"
+	"github.com/pingcap/tidiff/sqllogictest"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var sqllogictestCommand = &cli.Command{
+	Name:      "sqllogictest",
+	Usage:     "Execute sqllogictest files and report the differences",
+	ArgsUsage: "file.test|dir...",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Print the results of all records instead of the differed and errored records only",
+		},
+	},
+	Action: runSQLLogicTest,
+}
+
+func runSQLLogicTest(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("test files are required")
+	}
+	files, err := testFiles(ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	exec, err := openExecutor(ctx)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+
+	out := newOutput(ctx)
+	total := &script.Summary{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		records, err := sqllogictest.Parse(file, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if record.Kind == sqllogictest.Unknown {
+				fmt.Fprintf(os.Stderr, "%s:%d: unknown record %q, skipped\n", record.File, record.Line, record.SQL)
+			}
+		}
+		// The hash threshold is reset for every file
+		runner := &sqllogictest.Runner{
+			Executor: exec,
+			Handler:  out.handle,
+		}
+		total.Merge(runner.Run(records))
+	}
+	return out.finish(total)
+}
@@ -0,0 +1,199 @@
+// Package sqllogictest runs the test files of sqllogictest in all backends, the
+// expected result types and sort modes of queries drive the comparison.
+package sqllogictest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a record.
+type Kind int
+
+const (
+	// Statement is a statement expected to succeed or fail.
+	Statement Kind = iota
+	// Query is a query with the expected result types and sort mode.
+	Query
+	// HashThreshold changes the number of values above which the results are hashed.
+	HashThreshold
+	// Halt stops the test file.
+	Halt
+	// Unknown is a record of an unknown kind, e.g. `control`, which is skipped.
+	Unknown
+)
+
+// SortMode is how the results of a query are sorted before comparing.
+type SortMode string
+
+const (
+	NoSort    SortMode = "nosort"
+	RowSort   SortMode = "rowsort"
+	ValueSort SortMode = "valuesort"
+)
+
+// Record is a record of a test file.
+type Record struct {
+	Kind Kind
+	// SQL is the text of the statement or query, or the first line of Unknown records.
+	SQL string
+	// ExpectError reports whether the statement or query is expected to fail.
+	ExpectError bool
+	// Types are the expected result types of a query, one character per column:
+	// I for integers, R for floating point numbers and T for texts.
+	Types    string
+	SortMode SortMode
+	Label    string
+	// Threshold is the hash threshold of HashThreshold records.
+	Threshold int
+	File      string
+	// Line is the line number of the first line of the record.
+	Line int
+}
+
+// engine is the database engine name in the `skipif` and `onlyif` conditions.
+const engine = "mysql"
+
+// Parse parses a test file into records. The records separated by blank lines
+// start with `statement`, `query`, `hash-threshold` or `halt`, preceded by the
+// optional `skipif`/`onlyif` conditions. The records which are skipped for the
+// mysql engine are excluded, the records of other kinds are kept as Unknown
+// records to be reported and skipped, and the expected results of queries are
+// ignored since the backends are compared with each other.
+func Parse(file string, r io.Reader) ([]*Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	var records []*Record
+	var lines []string
+	var start, line int
+	flush := func() error {
+		defer func() { lines = lines[:0] }()
+		if len(lines) == 0 {
+			return nil
+		}
+		record, skip, err := parseRecord(lines)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", file, start, err)
+		}
+		if !skip {
+			record.File, record.Line = file, start
+			records = append(records, record)
+		}
+		return nil
+	}
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case text == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(text, "#") && len(lines) == 0:
+			// Comments between records
+		default:
+			if len(lines) == 0 {
+				start = line
+			}
+			lines = append(lines, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// parseRecord parses the lines of a record, and reports whether the record is
+// skipped for the mysql engine.
+func parseRecord(lines []string) (*Record, bool, error) {
+	var skip bool
+	for len(lines) > 0 {
+		fields := strings.Fields(lines[0])
+		if fields[0] != "skipif" && fields[0] != "onlyif" {
+			break
+		}
+		if len(fields) < 2 {
+			return nil, false, fmt.Errorf("missing engine of %s", fields[0])
+		}
+		if (fields[0] == "skipif") == (fields[1] == engine) {
+			skip = true
+		}
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, false, errors.New("missing record after conditions")
+	}
+
+	fields := strings.Fields(lines[0])
+	record := &Record{}
+	switch fields[0] {
+	case "statement":
+		record.Kind = Statement
+		if len(fields) < 2 || (fields[1] != "ok" && fields[1] != "error") {
+			return nil, false, fmt.Errorf("invalid statement record %q", lines[0])
+		}
+		record.ExpectError = fields[1] == "error"
+	case "query":
+		record.Kind = Query
+		if len(fields) < 2 {
+			return nil, false, errors.New("missing types of query record")
+		}
+		if fields[1] == "error" {
+			record.ExpectError = true
+		} else {
+			record.Types = fields[1]
+		}
+		record.SortMode = NoSort
+		if len(fields) > 2 {
+			record.SortMode = SortMode(fields[2])
+			switch record.SortMode {
+			case NoSort, RowSort, ValueSort:
+			default:
+				return nil, false, fmt.Errorf("invalid sort mode %s", fields[2])
+			}
+		}
+		if len(fields) > 3 {
+			record.Label = fields[3]
+		}
+	case "hash-threshold":
+		record.Kind = HashThreshold
+		if len(fields) < 2 {
+			return nil, false, errors.New("missing hash threshold")
+		}
+		threshold, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid hash threshold %s", fields[1])
+		}
+		record.Threshold = threshold
+		return record, skip, nil
+	case "halt":
+		record.Kind = Halt
+		return record, skip, nil
+	default:
+		// Records of other harnesses or dialects don't fail the rest of the file
+		record.Kind = Unknown
+		record.SQL = lines[0]
+		return record, skip, nil
+	}
+
+	var sql []string
+	for _, line := range lines[1:] {
+		if line == "----" {
+			break
+		}
+		sql = append(sql, line)
+	}
+	if len(sql) == 0 {
+		return nil, false, errors.New("missing SQL")
+	}
+	record.SQL = strings.Join(sql, "\n")
+	return record, skip, nil
+}
@@ -0,0 +1,90 @@
+package sqllogictest
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := `# comment
+statement ok
+CREATE TABLE t1(a INTEGER,
+  b INTEGER)
+
+query IT rowsort label-1
+SELECT a, b
+  FROM t1
+----
+1
+x
+
+skipif mysql
+statement ok
+SELECT 1
+
+onlyif mysql
+query error
+SELECT * FROM nosuch
+
+hash-threshold 8
+
+control sortmode rowsort
+
+skipif mysql
+control other
+
+onlyif mssql
+halt
+
+halt
+`
+	expected := []*Record{
+		{Kind: Statement, SQL: "CREATE TABLE t1(a INTEGER,\n  b INTEGER)", Line: 2},
+		{Kind: Query, SQL: "SELECT a, b\n  FROM t1", Types: "IT", SortMode: RowSort, Label: "label-1", Line: 6},
+		{Kind: Query, SQL: "SELECT * FROM nosuch", ExpectError: true, SortMode: NoSort, Line: 17},
+		{Kind: HashThreshold, Threshold: 8, Line: 21},
+		{Kind: Unknown, SQL: "control sortmode rowsort", Line: 23},
+		{Kind: Halt, Line: 31},
+	}
+	for _, record := range expected {
+		record.File = "a.test"
+	}
+	records, err := Parse("a.test", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, expected) {
+		for _, record := range records {
+			t.Logf("%+v", *record)
+		}
+		t.Fatalf("unexpected records")
+	}
+
+	if _, err := Parse("a.test", strings.NewReader("query II unknown\nSELECT 1")); err == nil {
+		t.Fatal("expect error for unknown sort mode")
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	cases := []struct {
+		t        byte
+		cell     sql.NullString
+		expected string
+	}{
+		{'I', sql.NullString{String: "42", Valid: true}, "42"},
+		{'I', sql.NullString{String: "-1.9", Valid: true}, "-1"},
+		{'I', sql.NullString{String: "abc", Valid: true}, "0"},
+		{'R', sql.NullString{String: "1.23456", Valid: true}, "1.235"},
+		{'R', sql.NullString{String: "2", Valid: true}, "2.000"},
+		{'T', sql.NullString{String: "", Valid: true}, "(empty)"},
+		{'T', sql.NullString{String: "a\tb", Valid: true}, "a@b"},
+		{'T', sql.NullString{}, "NULL"},
+	}
+	for _, c := range cases {
+		if got := formatValue(c.t, c.cell); got != c.expected {
+			t.Fatalf("format %c %v: expected %s, got %s", c.t, c.cell, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package sqllogictest
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidiff/executor"
+	"github.com/pingcap/tidiff/script"
+)
+
+// Runner executes the records of test files in all backends one by one, and
+// hands the result of every statement and query to the handler.
+type Runner struct {
+	Executor *executor.Executor
+	Handler  func(*script.Result)
+	// Threshold is the number of values above which the results are compared by
+	// the hash of the values, 0 to never hash. It's changed by `hash-threshold`.
+	Threshold int
+}
+
+// Run executes the records of a test file. Failed or differed records don't
+// stop the test, only `halt` does. Unknown records are skipped.
+func (r *Runner) Run(records []*Record) *script.Summary {
+	summary := &script.Summary{}
+	for _, record := range records {
+		switch record.Kind {
+		case HashThreshold:
+			r.Threshold = record.Threshold
+			continue
+		case Halt:
+			return summary
+		case Unknown:
+			continue
+		}
+		result := r.exec(record)
+		summary.Add(result)
+		if r.Handler != nil {
+			r.Handler(result)
+		}
+	}
+	return summary
+}
+
+// exec executes a statement or query in all backends, the results of queries
+// are formatted by the expected types and sorted by the sort mode before comparing.
+func (r *Runner) exec(record *Record) *script.Result {
+	text := record.SQL
+	if record.Kind == Query && !record.ExpectError {
+		// The rows are sorted by the sort mode, or compared as they are if not sorted
+		text = "/* tidiff:ordered */ " + text
+	}
+	stmt := &script.Statement{Text: text, File: record.File, Line: record.Line}
+	results, err := r.Executor.Query(text)
+	if err != nil {
+		return &script.Result{Statement: stmt, Err: err}
+	}
+	if record.Kind == Query {
+		for _, result := range results {
+			if result.Error == nil {
+				r.format(record, result)
+			}
+		}
+	}
+	c := r.Executor.Compare(results)
+	// The records expect any error
+	expected := record.ExpectError && c.ExpectErrors(func(error) bool { return true })
+	return &script.Result{Statement: stmt, Comparison: c, Expected: expected}
+}
+
+// format formats the result set like sqllogictest: the cells are formatted by
+// the expected types, the rows or values are sorted by the sort mode, and the
+// values are replaced by their hash if there are more than the threshold.
+func (r *Runner) format(record *Record, result *executor.QueryResult) {
+	for _, row := range result.Rows {
+		for col := range row {
+			var t byte = 'T'
+			if col < len(record.Types) {
+				t = record.Types[col]
+			}
+			row[col] = sql.NullString{String: formatValue(t, row[col]), Valid: true}
+		}
+	}
+
+	switch record.SortMode {
+	case RowSort:
+		sort.SliceStable(result.Rows, func(i, j int) bool {
+			return lessRow(result.Rows[i], result.Rows[j])
+		})
+	case ValueSort:
+		var values [][]sql.NullString
+		for _, row := range result.Rows {
+			for _, cell := range row {
+				values = append(values, []sql.NullString{cell})
+			}
+		}
+		sort.SliceStable(values, func(i, j int) bool {
+			return values[i][0].String < values[j][0].String
+		})
+		result.Columns, result.ColumnTypes, result.Rows = []string{"value"}, nil, values
+	}
+
+	n := len(result.Rows) * len(result.Columns)
+	if r.Threshold > 0 && n > r.Threshold {
+		h := md5.New()
+		for _, row := range result.Rows {
+			for _, cell := range row {
+				h.Write([]byte(cell.String + "\n"))
+			}
+		}
+		hash := fmt.Sprintf("%d values hashing to %x", n, h.Sum(nil))
+		result.Columns, result.ColumnTypes = []string{"hash"}, nil
+		result.Rows = [][]sql.NullString{{{String: hash, Valid: true}}}
+	}
+}
+
+func lessRow(a, b []sql.NullString) bool {
+	for i := range a {
+		if a[i].String != b[i].String {
+			return a[i].String < b[i].String
+		}
+	}
+	return false
+}
+
+// formatValue formats a cell by the expected type like sqllogictest. NULL is
+// `NULL`, integers are truncated, floating point numbers have 3 fractional
+// digits, and empty texts are `(empty)` with unprintable characters as `@`.
+func formatValue(t byte, cell sql.NullString) string {
+	if !cell.Valid {
+		return "NULL"
+	}
+	switch t {
+	case 'I':
+		if i, err := strconv.ParseInt(cell.String, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		f, err := strconv.ParseFloat(cell.String, 64)
+		if err != nil {
+			return "0"
+		}
+		return strconv.FormatInt(int64(math.Trunc(f)), 10)
+	case 'R':
+		f, err := strconv.ParseFloat(cell.String, 64)
+		if err != nil {
+			f = 0
+		}
+		return fmt.Sprintf("%.3f", f)
+	}
+	if cell.String == "" {
+		return "(empty)"
+	}
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '@'
+		}
+		return r
+	}, cell.String)
+}
@@ -0,0 +1,190 @@
+// Package suppress declares the known differences between backends, which are
+// suppressed instead of reported as differences.
+package suppress
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/tidiff/diff"
+)
+
+// Rule declares the known differences of the statements it matches. A rule
+// without columns, errors or patterns suppresses all differences.
+type Rule struct {
+	// Name identifies the rule in the outputs, the index of the rule is used if empty.
+	Name string `json:"name,omitempty"`
+	// Match is a regular expression matching the statements.
+	Match string `json:"match,omitempty"`
+	// Fingerprint matches the statements with the same fingerprint, see Fingerprint.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Backends are the names of backends whose differences are suppressed, all
+	// backends if empty.
+	Backends []string `json:"backends,omitempty"`
+	// Columns are the names of columns whose values are ignored.
+	Columns []string `json:"columns,omitempty"`
+	// Errors are the error codes which are ignored if either side fails with.
+	Errors []uint16 `json:"errors,omitempty"`
+	// Patterns are regular expressions removed from the values and warnings
+	// before comparing.
+	Patterns []string `json:"patterns,omitempty"`
+
+	match       *regexp.Regexp
+	fingerprint string
+	patterns    []*regexp.Regexp
+}
+
+// Rules are the rules of a suppression file.
+type Rules []*Rule
+
+// Load reads the rules from a JSON file, which is an array of rules.
+func Load(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid suppression file %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// Parse parses the rules in JSON.
+func Parse(data []byte) (Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Match == "" && r.Fingerprint == "" {
+		return errors.New("match or fingerprint is required")
+	}
+	var err error
+	if r.Match != "" {
+		if r.match, err = regexp.Compile(r.Match); err != nil {
+			return err
+		}
+	}
+	if r.Fingerprint != "" {
+		r.fingerprint = Fingerprint(r.Fingerprint)
+	}
+	for _, pattern := range r.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return nil
+}
+
+// Find returns the rules matching the statement for the backend.
+func (rules Rules) Find(query, backend string) []*Rule {
+	var result []*Rule
+	var fingerprint string
+	for _, rule := range rules {
+		if len(rule.Backends) > 0 && !contains(rule.Backends, backend) {
+			continue
+		}
+		if rule.match != nil && !rule.match.MatchString(query) {
+			continue
+		}
+		if rule.fingerprint != "" {
+			if fingerprint == "" {
+				fingerprint = Fingerprint(query)
+			}
+			if rule.fingerprint != fingerprint {
+				continue
+			}
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// All reports whether the rule suppresses all differences.
+func (r *Rule) All() bool {
+	return len(r.Columns) == 0 && len(r.Errors) == 0 && len(r.Patterns) == 0
+}
+
+// IgnoreError reports whether the error code is ignored.
+func (r *Rule) IgnoreError(code uint16) bool {
+	for _, c := range r.Errors {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns a copy of the result set with the ignored columns cleared and
+// the patterns removed from the values.
+func (r *Rule) Apply(rs *diff.ResultSet) *diff.ResultSet {
+	ignored := map[int]bool{}
+	for i, name := range rs.Columns {
+		ignored[i] = contains(r.Columns, name)
+	}
+	result := &diff.ResultSet{Columns: rs.Columns, Types: rs.Types}
+	for _, row := range rs.Rows {
+		cells := make([]sql.NullString, len(row))
+		for i, cell := range row {
+			if ignored[i] {
+				continue
+			}
+			cells[i] = cell
+			for _, re := range r.patterns {
+				cells[i].String = re.ReplaceAllString(cells[i].String, "")
+			}
+		}
+		result.Rows = append(result.Rows, cells)
+	}
+	return result
+}
+
+var (
+	numberLiteral = regexp.MustCompile(`\b(0x[0-9a-f]+|\d+(\.\d*)?(e[+-]?\d+)?)\b`)
+	stringLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	comments      = regexp.MustCompile(`(?s)/\*.*?\*/|(--\s|#)[^\n]*`)
+	tokens        = regexp.MustCompile("`[^`]*`|@@?[\\w.$]+|\\w+|[^\\w\\s]")
+	placeholders  = regexp.MustCompile(`\?( , \?)+`)
+)
+
+// Fingerprint returns the fingerprint of the statement, which is the tokens of
+// the statement in lower case separated by single spaces, with the comments
+// removed, the literals replaced by `?` and the lists of literals collapsed.
+// For example, the fingerprint of `SELECT * FROM t WHERE a IN (1, 2) AND b='x'`
+// is `select * from t where a in ( ? ) and b = ?`.
+func Fingerprint(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = comments.ReplaceAllString(query, " ")
+	query = numberLiteral.ReplaceAllString(strings.ToLower(query), "?")
+	query = strings.Join(tokens.FindAllString(query, -1), " ")
+	query = placeholders.ReplaceAllString(query, "?")
+	return strings.TrimSuffix(query, " ;")
+}
@@ -0,0 +1,79 @@
+package suppress
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/pingcap/tidiff/diff"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM t WHERE a IN (1, 2,3) AND b = 'x';":      "select * from t where a in ( ? ) and b = ?",
+		"select  /* hint */ 1.5e3,\n -0x1F, \"it\\\"s\" -- end": "select ? , - ?",
+		"select `a b`,@@Global.x>=t.c from t2":                  "select `a b` , @@global.x > = t . c from t2",
+		"select a1 from t2":                                     "select a1 from t2",
+	}
+	for query, expected := range cases {
+		if f := Fingerprint(query); f != expected {
+			t.Errorf("Fingerprint(%q) = %q, expected %q", query, f, expected)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	rules, err := Parse([]byte(`[
+		{"name": "version", "match": "(?i)version\\(\\)"},
+		{"fingerprint": "select @@tidb_mem_quota_query + 1", "backends": ["TiDB"]},
+		{"name": "invalid"}
+	]`))
+	if err == nil {
+		t.Fatal("expected the rule without match or fingerprint to be invalid")
+	}
+	rules, err = Parse([]byte(`[
+		{"name": "version", "match": "(?i)version\\(\\)"},
+		{"fingerprint": "select @@tidb_mem_quota_query + 1", "backends": ["TiDB"]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(query, backend string) []string {
+		var result []string
+		for _, rule := range rules.Find(query, backend) {
+			result = append(result, rule.Name)
+		}
+		return result
+	}
+	if found := names("SELECT VERSION()", "MySQL"); !reflect.DeepEqual(found, []string{"version"}) {
+		t.Fatalf("unexpected rules %v", found)
+	}
+	if found := names("select @@tidb_mem_quota_query+20", "tidb"); !reflect.DeepEqual(found, []string{"#2"}) {
+		t.Fatalf("unexpected rules %v", found)
+	}
+	if found := names("select @@tidb_mem_quota_query+20", "MySQL"); found != nil {
+		t.Fatalf("unexpected rules %v", found)
+	}
+}
+
+func TestApply(t *testing.T) {
+	rules, err := Parse([]byte(`[{"match": "show create table", "columns": ["Table"], "patterns": [" ?/\\*T!\\[clustered_index\\] [A-Z]+ \\*/"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &diff.ResultSet{
+		Columns: []string{"Table", "Create Table"},
+		Rows: [][]sql.NullString{{
+			{String: "t", Valid: true},
+			{String: "PRIMARY KEY (`a`) /*T![clustered_index] CLUSTERED */", Valid: true},
+		}},
+	}
+	applied := rules[0].Apply(rs)
+	expected := [][]sql.NullString{{{}, {String: "PRIMARY KEY (`a`)", Valid: true}}}
+	if !reflect.DeepEqual(applied.Rows, expected) {
+		t.Fatalf("unexpected rows %v", applied.Rows)
+	}
+	if rs.Rows[0][0].String != "t" {
+		t.Fatal("expected the result set unchanged")
+	}
+}
@@ -1,13 +1,13 @@
 package uimode
 
 import (
-	"bytes"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell"
-	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/pingcap/tidiff/diff"
+	"github.com/pingcap/tidiff/executor"
 )
 
 func (ui *UI) handleEvents() {
@@ -15,11 +15,16 @@ func (ui *UI) handleEvents() {
 	ui.history.SetInputCapture(ui.handleHistory)
 	ui.sqlStmt.SetDoneFunc(ui.sqlStmtDone)
 	ui.sqlStmt.SetInputCapture(ui.sqlStmtKey)
-	ui.mysqlPanel.SetInputCapture(ui.esc)
-	ui.tidbPanel.SetInputCapture(ui.esc)
+	for _, panel := range ui.panels {
+		panel.SetInputCapture(ui.esc)
+	}
 }
 
 func (ui *UI) handleApp(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyCtrlR {
+		ui.reconnect()
+		return nil
+	}
 	if event.Key() != tcell.KeyTAB {
 		return event
 	}
@@ -40,6 +45,19 @@ func (ui *UI) handleApp(event *tcell.EventKey) *tcell.EventKey {
 	return event
 }
 
+// reconnect starts new sessions for all backends, which resets all session states
+// such as the current database, session variables and open transactions.
+func (ui *UI) reconnect() {
+	err := ui.executor.Reconnect()
+	for _, panel := range ui.panels {
+		if err != nil {
+			fmt.Fprintln(panel, "[red]"+err.Error()+"[white]\n")
+			continue
+		}
+		fmt.Fprint(panel, "[yellow]-- session reconnected[white]\n\n")
+	}
+}
+
 func (ui *UI) esc(event *tcell.EventKey) *tcell.EventKey {
 	if event.Key() != tcell.KeyESC {
 		return event
@@ -78,57 +96,58 @@ func (ui *UI) handleHistory(event *tcell.EventKey) *tcell.EventKey {
 	return event
 }
 
-func (ui *UI) query(query string) {
+// query executes the query and returns the text to record in the history,
+// which is the template with its seed if the query is a template.
+func (ui *UI) query(query string) string {
 	if query == "" {
-		return
+		return query
 	}
 
-	mysqlResult, tidbResult, err := ui.executor.Query(query)
+	results, err := ui.executor.Query(query)
 	if err != nil {
-		ui.recordHistory(fmt.Sprintf("%s /*->[red] %s[white]*/", query, err.Error()))
-		return
+		return fmt.Sprintf("%s /*->[red] %s[white]*/", query, err.Error())
 	}
-	defer mysqlResult.Close()
-	defer tidbResult.Close()
 
 	// Highlight diff
-	mysqlContent, tidbContent := mysqlResult.Content(), tidbResult.Content()
-	if mysqlResult.Error == nil && tidbResult.Error == nil {
-		patch := diffmatchpatch.New()
-		diff := patch.DiffMain(mysqlContent, tidbContent, false)
-		if ui.recorder.IsDiffEnable() {
-			ui.recorder.LogDiff(patch.DiffPrettyText(diff))
-		}
-		var newMySQLContent, newTiDBContent bytes.Buffer
-		for _, d := range diff {
-			switch d.Type {
-			case diffmatchpatch.DiffEqual:
-				newMySQLContent.WriteString(d.Text)
-				newTiDBContent.WriteString(d.Text)
-			case diffmatchpatch.DiffDelete:
-				newMySQLContent.WriteString("[red]" + d.Text + "[white]")
-			case diffmatchpatch.DiffInsert:
-				newTiDBContent.WriteString("[green]" + d.Text + "[white]")
+	ref := ui.executor.Reference()
+	red := func(a ...interface{}) string { return "[red]" + fmt.Sprint(a...) + "[white]" }
+	green := func(a ...interface{}) string { return "[green]" + fmt.Sprint(a...) + "[white]" }
+	c := ui.executor.Compare(results)
+	if ui.recorder.IsDiffEnable() {
+		for i, d := range c.Diffs {
+			if d != nil {
+				ui.recorder.LogDiff(diff.Unified(results[ref].ResultSet(), results[i].ResultSet(), d))
 			}
 		}
-		mysqlContent = newMySQLContent.String()
-		tidbContent = newTiDBContent.String()
 	}
+	contents, stats, warnings := c.Render(red, green)
 
 	logQuery := query
 	if strings.HasPrefix(query, "!!") {
-		logQuery = mysqlResult.Rendered
+		logQuery = results[ref].Rendered
 	}
-	fmt.Fprintln(ui.mysqlPanel, fmt.Sprintf("MySQL(%s)> %s", ui.executor.MySQLConfig.Address(), logQuery))
-	fmt.Fprintln(ui.tidbPanel, fmt.Sprintf("TiDB(%s)> %s", ui.executor.TiDBConfig.Address(), logQuery))
-	if mysqlContent != "" {
-		fmt.Fprintln(ui.mysqlPanel, mysqlContent)
+	for i, result := range results {
+		panel := ui.panels[i]
+		if result.Template {
+			fmt.Fprintf(panel, "[yellow]-- seed: %d[white]\n", result.Seed)
+		}
+		fmt.Fprintln(panel, fmt.Sprintf("%s(%s)> %s", result.Backend.Name, result.Backend.Config.Address(), logQuery))
+		if contents[i] != "" {
+			fmt.Fprintln(panel, contents[i])
+		}
+		fmt.Fprintln(panel, stats[i])
+		if warnings[i] != "" {
+			fmt.Fprintln(panel, "[yellow]"+warnings[i]+"[white]")
+		}
+		if c.Verdicts[i] != "" {
+			fmt.Fprintln(panel, "[yellow]-- "+c.Verdicts[i]+"[white]")
+		}
+		fmt.Fprintln(panel)
 	}
-	fmt.Fprintln(ui.mysqlPanel, mysqlResult.Stat()+"\n")
-	if tidbContent != "" {
-		fmt.Fprintln(ui.tidbPanel, tidbContent)
+	if results[ref].Template {
+		return executor.WithSeed(query, results[ref].Seed)
 	}
-	fmt.Fprintln(ui.tidbPanel, tidbResult.Stat()+"\n")
+	return query
 }
 
 func (ui *UI) sqlStmtDone(key tcell.Key) {
@@ -136,8 +155,7 @@ func (ui *UI) sqlStmtDone(key tcell.Key) {
 		return
 	}
 	query := strings.TrimSpace(ui.sqlStmt.GetText())
-	ui.query(query)
-	ui.recordHistory(query)
+	ui.recordHistory(ui.query(query))
 }
 
 func (ui *UI) recordHistory(query string) {
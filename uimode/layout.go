@@ -18,24 +18,30 @@ func (ui *UI) layout() {
 		AddItem(history, 0, 1, false).
 		AddItem(sqlStmt, 1, 1, false)
 
-	// Result sets panels (MySQL result and TiDB results)
-	mysqlPanel := tview.NewTextView()
-	mysqlPanel.SetBorder(true).SetTitle("MySQL").SetBorderPadding(0, 0, 1, 1)
-	mysqlPanel.SetDynamicColors(true).SetRegions(true)
-	tidbPanel := tview.NewTextView()
-	tidbPanel.SetBorder(true).SetTitle("TiDB").SetBorderPadding(0, 0, 1, 1)
-	tidbPanel.SetDynamicColors(true).SetRegions(true)
-	resultSets := tview.NewFlex().SetDirection(tview.FlexColumn).
-		AddItem(mysqlPanel, 0, 1, false).
-		AddItem(tidbPanel, 0, 1, false)
+	// Result sets panels (one panel for each backend)
+	resultSets := tview.NewFlex().SetDirection(tview.FlexColumn)
+	var panels []*tview.TextView
+	for i, b := range ui.executor.Backends {
+		title := b.Name
+		if i == ui.executor.Reference() {
+			title += " (reference)"
+		}
+		panel := tview.NewTextView()
+		panel.SetBorder(true).SetTitle(title).SetBorderPadding(0, 0, 1, 1)
+		panel.SetDynamicColors(true).SetRegions(true)
+		resultSets.AddItem(panel, 0, 1, false)
+		panels = append(panels, panel)
+	}
 
 	// Key `TAB` will switch focus around focusable widgets, all panels which want get focus
 	// on `TAB` hit should be placed in `ui.focusables` slice
 	ui.sqlStmt = sqlStmt
 	ui.history = history
-	ui.mysqlPanel = mysqlPanel
-	ui.tidbPanel = tidbPanel
-	ui.focusables = []tview.Primitive{sqlStmt, history, mysqlPanel, tidbPanel}
+	ui.panels = panels
+	ui.focusables = []tview.Primitive{sqlStmt, history}
+	for _, panel := range panels {
+		ui.focusables = append(ui.focusables, panel)
+	}
 
 	// Restore history query
 	if histories := ui.recorder.Items(); len(histories) > 0 {
@@ -48,7 +54,7 @@ func (ui *UI) layout() {
 		history.SetCurrentItem(0)
 	}
 
-	// Display MySQL/TiDB version information
+	// Display the version information of all backends
 	ui.query("select version()")
 
 	container := tview.NewFlex().SetDirection(tview.FlexRow).
@@ -12,10 +12,9 @@ type UI struct {
 	executor *executor.Executor
 
 	// panels
-	sqlStmt    *tview.InputField
-	history    *tview.List
-	mysqlPanel *tview.TextView
-	tidbPanel  *tview.TextView
+	sqlStmt *tview.InputField
+	history *tview.List
+	panels  []*tview.TextView // result panels in the order of backends
 
 	focusables []tview.Primitive
 }